@@ -0,0 +1,7 @@
+// Package v1alpha1 holds a minimal subset of the autopilot.libopenstorage.org types of
+// Portworx Autopilot (libopenstorage/autopilot-api). Conditions are kept as raw JSON,
+// fields not declared here are dropped when decoding.
+//
+// +k8s:deepcopy-gen=package
+// +groupName=autopilot.libopenstorage.org
+package v1alpha1
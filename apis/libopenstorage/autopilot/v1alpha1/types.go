@@ -0,0 +1,70 @@
+package v1alpha1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AutopilotRuleResourcePlural is "autopilotrules"
+	AutopilotRuleResourcePlural = "autopilotrules"
+
+	// VolumeActionPrefix is the prefix of all actions acting on a single volume.
+	VolumeActionPrefix = "openstorage.io.action.volume/"
+	// VolumeResizeAction grows a volume.
+	VolumeResizeAction = VolumeActionPrefix + "resize"
+	// ResizeMaxSizeParam is the size a volume is never grown beyond.
+	ResizeMaxSizeParam = "maxsize"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AutopilotRule is a cluster scoped rule running actions on objects matching the
+// selectors once the conditions are met.
+type AutopilotRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AutopilotRuleSpec `json:"spec"`
+}
+
+// AutopilotRuleSpec is the specification of a rule.
+type AutopilotRuleSpec struct {
+	// Enforcement is the enforcement type of the rule, e.g. required or approvalRequired
+	Enforcement string `json:"enforcement,omitempty"`
+	// Selector selects the objects, e.g. PVCs, the rule applies to
+	Selector RuleObjectSelector `json:"selector,omitempty"`
+	// NamespaceSelector selects the namespaces of the objects the rule applies to
+	NamespaceSelector RuleObjectSelector `json:"namespaceSelector,omitempty"`
+	// Conditions trigger the actions of the rule
+	Conditions *apiextensionsv1.JSON `json:"conditions,omitempty"`
+	// Actions are run once the conditions are met
+	Actions []RuleAction `json:"actions,omitempty"`
+	// ActionsCoolDownPeriod is the time in seconds to wait between two actions
+	ActionsCoolDownPeriod int64 `json:"actionsCoolDownPeriod,omitempty"`
+	// PollInterval is the interval in seconds the conditions are checked in
+	PollInterval int64 `json:"pollInterval,omitempty"`
+}
+
+// RuleObjectSelector selects objects by label.
+type RuleObjectSelector struct {
+	metav1.LabelSelector `json:",inline"`
+}
+
+// RuleAction is an action of a rule.
+type RuleAction struct {
+	// Name is the name of the action, e.g. openstorage.io.action.volume/resize
+	Name string `json:"name"`
+	// Params are the parameters of the action
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AutopilotRuleList is a list of AutopilotRules.
+type AutopilotRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []AutopilotRule `json:"items"`
+}
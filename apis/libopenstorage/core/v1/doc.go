@@ -0,0 +1,7 @@
+// Package v1 holds a minimal, read-only subset of the core.libopenstorage.org types of
+// the Portworx operator (libopenstorage/operator). Only the fields shown to tenants are
+// declared, everything else is dropped when decoding host objects.
+//
+// +k8s:deepcopy-gen=package
+// +groupName=core.libopenstorage.org
+package v1
@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// StorageClusterResourcePlural is "storageclusters"
+	StorageClusterResourcePlural = "storageclusters"
+	// StorageNodeResourcePlural is "storagenodes"
+	StorageNodeResourcePlural = "storagenodes"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StorageCluster represents a Portworx cluster.
+type StorageCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status is the most recently observed status of the cluster
+	Status StorageClusterStatus `json:"status,omitempty"`
+}
+
+// StorageClusterStatus is the observed status of a cluster.
+type StorageClusterStatus struct {
+	// ClusterName is the name of the Portworx cluster
+	ClusterName string `json:"clusterName,omitempty"`
+	// Phase is the current phase of the cluster, e.g. Initializing, Online
+	Phase string `json:"phase,omitempty"`
+	// Version is the Portworx version of the cluster
+	Version string `json:"version,omitempty"`
+	// Conditions are the conditions of the cluster components
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+}
+
+// ClusterCondition is the condition of a cluster component.
+type ClusterCondition struct {
+	// Source is the component reporting the condition
+	Source string `json:"source,omitempty"`
+	// Type is the type of the condition
+	Type string `json:"type,omitempty"`
+	// Status is the status of the condition
+	Status string `json:"status,omitempty"`
+	// Message is a human readable description of the condition
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StorageClusterList is a list of StorageClusters.
+type StorageClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []StorageCluster `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StorageNode represents a Portworx node. StorageNodes are named after the Kubernetes
+// node they run on.
+type StorageNode struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the configuration of the node
+	Spec StorageNodeSpec `json:"spec,omitempty"`
+	// Status is the most recently observed status of the node
+	Status StorageNodeStatus `json:"status,omitempty"`
+}
+
+// StorageNodeSpec is the configuration of a node.
+type StorageNodeSpec struct {
+	// Version is the Portworx version of the node
+	Version string `json:"version,omitempty"`
+}
+
+// StorageNodeStatus is the observed status of a node.
+type StorageNodeStatus struct {
+	// Phase is the current phase of the node, e.g. Online, Degraded
+	Phase string `json:"phase,omitempty"`
+	// Storage is the capacity of the node
+	Storage NodeStorageStatus `json:"storage,omitempty"`
+	// Conditions are the conditions of the node
+	Conditions []NodeCondition `json:"conditions,omitempty"`
+}
+
+// NodeStorageStatus is the capacity of a node.
+type NodeStorageStatus struct {
+	// TotalSize is the total storage capacity of the node
+	TotalSize resource.Quantity `json:"totalSize,omitempty"`
+	// UsedSize is the used storage capacity of the node
+	UsedSize resource.Quantity `json:"usedSize,omitempty"`
+}
+
+// NodeCondition is a condition of a node.
+type NodeCondition struct {
+	// Type is the type of the condition
+	Type string `json:"type,omitempty"`
+	// Status is the status of the condition
+	Status string `json:"status,omitempty"`
+	// Reason is a short reason for the condition
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable description of the condition
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is the last time the status changed
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StorageNodeList is a list of StorageNodes.
+type StorageNodeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []StorageNode `json:"items"`
+}
@@ -0,0 +1,6 @@
+// Package v1alpha1 holds a minimal subset of the stork.libopenstorage.org types of
+// Stork (libopenstorage/stork). Fields not declared here are dropped when decoding.
+//
+// +k8s:deepcopy-gen=package
+// +groupName=stork.libopenstorage.org
+package v1alpha1
@@ -0,0 +1,239 @@
+package v1alpha1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RuleResourcePlural is "rules"
+	RuleResourcePlural = "rules"
+	// ClusterPairResourcePlural is "clusterpairs"
+	ClusterPairResourcePlural = "clusterpairs"
+	// MigrationResourcePlural is "migrations"
+	MigrationResourcePlural = "migrations"
+	// MigrationScheduleResourcePlural is "migrationschedules"
+	MigrationScheduleResourcePlural = "migrationschedules"
+
+	// PreSnapshotRuleAnnotation names the Rule executed before a snapshot is taken.
+	PreSnapshotRuleAnnotation = "stork.libopenstorage.org/pre-snapshot-rule"
+	// PostSnapshotRuleAnnotation names the Rule executed after a snapshot was taken.
+	PostSnapshotRuleAnnotation = "stork.libopenstorage.org/post-snapshot-rule"
+)
+
+// RuleActionType is the type of a rule action.
+type RuleActionType string
+
+const (
+	// RuleActionCommand runs the value of the action as command in the pods.
+	RuleActionCommand RuleActionType = "command"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Rule is a set of actions Stork executes in pods, e.g. to quiesce an application
+// before it is snapshotted.
+type Rule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Rules []RuleItem `json:"rules"`
+}
+
+// RuleItem are the actions executed in the pods matching the selector.
+type RuleItem struct {
+	// PodSelector selects the pods in the namespace of the rule the actions run in
+	PodSelector map[string]string `json:"podSelector"`
+	// Actions are executed in order
+	Actions []RuleAction `json:"actions"`
+	// Container is the container the actions run in, defaults to the first one
+	Container string `json:"container,omitempty"`
+}
+
+// RuleAction is a single action of a rule.
+type RuleAction struct {
+	// Type is the type of the action
+	Type RuleActionType `json:"type"`
+	// Background runs the action in the background
+	Background bool `json:"background,omitempty"`
+	// RunInSinglePod runs the action only in one of the selected pods
+	RunInSinglePod bool `json:"runInSinglePod,omitempty"`
+	// Value is the action itself, e.g. the command
+	Value string `json:"value"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RuleList is a list of rules.
+type RuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Rule `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterPair pairs the cluster with a remote cluster objects are migrated to.
+type ClusterPair struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterPairSpec   `json:"spec"`
+	Status ClusterPairStatus `json:"status,omitempty"`
+}
+
+// ClusterPairSpec is the specification of a cluster pair.
+type ClusterPairSpec struct {
+	// Config is the kubeconfig of the remote cluster, kept as raw JSON
+	Config *apiextensionsv1.JSON `json:"config,omitempty"`
+	// Options are the options of the storage driver, e.g. the remote Portworx endpoint
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// ClusterPairStatus is the status of a cluster pair.
+type ClusterPairStatus struct {
+	// SchedulerStatus is the status of the pairing with the remote scheduler
+	SchedulerStatus string `json:"schedulerStatus,omitempty"`
+	// StorageStatus is the status of the pairing with the remote storage
+	StorageStatus string `json:"storageStatus,omitempty"`
+	// RemoteStorageID is the ID of the remote storage cluster
+	RemoteStorageID string `json:"remoteStorageId,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterPairList is a list of cluster pairs.
+type ClusterPairList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterPair `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Migration migrates the resources and volumes of namespaces to a paired cluster.
+type Migration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MigrationSpec   `json:"spec"`
+	Status MigrationStatus `json:"status,omitempty"`
+}
+
+// MigrationSpec is the specification of a migration.
+type MigrationSpec struct {
+	// ClusterPair is the name of the cluster pair in the namespace of the migration
+	ClusterPair string `json:"clusterPair"`
+	// Namespaces are the namespaces migrated
+	Namespaces []string `json:"namespaces"`
+	// IncludeResources migrates the resources of the namespaces
+	IncludeResources *bool `json:"includeResources,omitempty"`
+	// IncludeVolumes migrates the volumes of the namespaces
+	IncludeVolumes *bool `json:"includeVolumes,omitempty"`
+	// StartApplications scales up the applications on the remote cluster
+	StartApplications *bool `json:"startApplications,omitempty"`
+	// PurgeDeletedResources deletes resources on the remote cluster which were deleted
+	PurgeDeletedResources *bool `json:"purgeDeletedResources,omitempty"`
+	// Selectors only migrate the resources matching all labels
+	Selectors map[string]string `json:"selectors,omitempty"`
+	// PreExecRule is the name of the Rule executed before the migration
+	PreExecRule string `json:"preExecRule,omitempty"`
+	// PostExecRule is the name of the Rule executed after the migration
+	PostExecRule string `json:"postExecRule,omitempty"`
+}
+
+// MigrationStatus is the status of a migration.
+type MigrationStatus struct {
+	// Stage is the current stage, e.g. Volumes, Applications or Final
+	Stage string `json:"stage,omitempty"`
+	// Status is the status of the current stage, e.g. InProgress or Successful
+	Status string `json:"status,omitempty"`
+	// Resources are the migrated resources
+	Resources []*MigrationResourceInfo `json:"resources,omitempty"`
+	// Volumes are the migrated volumes
+	Volumes []*MigrationVolumeInfo `json:"volumes,omitempty"`
+	// FinishTimestamp is the time the migration finished
+	FinishTimestamp metav1.Time `json:"finishTimestamp,omitempty"`
+}
+
+// MigrationResourceInfo is the status of a migrated resource.
+type MigrationResourceInfo struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+}
+
+// MigrationVolumeInfo is the status of a migrated volume.
+type MigrationVolumeInfo struct {
+	PersistentVolumeClaim string `json:"persistentVolumeClaim"`
+	Namespace             string `json:"namespace"`
+	Volume                string `json:"volume"`
+	Status                string `json:"status"`
+	Reason                string `json:"reason"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MigrationList is a list of migrations.
+type MigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Migration `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MigrationSchedule creates migrations from a template following a schedule policy.
+type MigrationSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MigrationScheduleSpec   `json:"spec"`
+	Status MigrationScheduleStatus `json:"status,omitempty"`
+}
+
+// MigrationScheduleSpec is the specification of a migration schedule.
+type MigrationScheduleSpec struct {
+	// Template is the template of the migrations created
+	Template MigrationTemplateSpec `json:"template"`
+	// SchedulePolicyName is the name of the cluster scoped SchedulePolicy
+	SchedulePolicyName string `json:"schedulePolicyName"`
+	// Suspend suspends creating new migrations
+	Suspend *bool `json:"suspend,omitempty"`
+}
+
+// MigrationTemplateSpec is the template of scheduled migrations.
+type MigrationTemplateSpec struct {
+	Spec MigrationSpec `json:"spec"`
+}
+
+// MigrationScheduleStatus is the status of a migration schedule.
+type MigrationScheduleStatus struct {
+	// Items are the migrations created per policy type, e.g. Interval or Daily
+	Items map[string][]*ScheduledMigrationStatus `json:"items,omitempty"`
+}
+
+// ScheduledMigrationStatus is the status of a migration created by a schedule.
+type ScheduledMigrationStatus struct {
+	Name              string      `json:"name"`
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+	FinishTimestamp   metav1.Time `json:"finishTimestamp"`
+	Status            string      `json:"status"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MigrationScheduleList is a list of migration schedules.
+type MigrationScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []MigrationSchedule `json:"items"`
+}
@@ -1,17 +1,195 @@
 package main
 
 import (
+	"os"
+
+	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	"github.com/loft-sh/vcluster-sdk/log"
 	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	"github.com/spf13/cobra"
 
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	autopilotv1alpha1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/autopilot/v1alpha1"
+	pxcorev1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/core/v1"
+	storkv1alpha1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/stork/v1alpha1"
+	"github.com/portworx/pxe-vcluster/internal/cli"
+	"github.com/portworx/pxe-vcluster/internal/config"
+	"github.com/portworx/pxe-vcluster/internal/hostguard"
+	"github.com/portworx/pxe-vcluster/internal/metrics"
+	"github.com/portworx/pxe-vcluster/internal/pxauth"
 	"github.com/portworx/pxe-vcluster/internal/syncers"
+	"github.com/portworx/pxe-vcluster/internal/webhook"
 )
 
 func main() {
+	rootCmd := &cobra.Command{
+		Use:          "pxe-vcluster",
+		Short:        "Portworx vcluster plugin and admin tooling",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		// without a subcommand the binary runs as vcluster plugin
+		Run: func(cmd *cobra.Command, args []string) {
+			runPlugin()
+		},
+	}
+	rootCmd.AddCommand(cli.NewOwnerCmd())
+	rootCmd.AddCommand(cli.NewListCmd())
+	rootCmd.AddCommand(cli.NewMigrateSnapshotsCmd())
+	rootCmd.AddCommand(cli.NewTeardownCmd())
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func runPlugin() {
+	cfg := config.MustLoad()
 	ctx := plugin.MustInit()
+	// the syncers translate all virtual namespaces into the single target namespace
+	if multiNamespaceMode, err := config.MultiNamespaceMode(ctx.Context); err != nil {
+		panic(err)
+	} else if multiNamespaceMode {
+		panic("multi-namespace mode of vcluster is not supported, disable multiNamespaceMode")
+	}
+	// guard the host client before any syncer is created, so all syncers share it. Syncers
+	// are wrapped in a resyncer, which syncs all their objects when the mode turns active
+	hostModes := hostguard.NewSwitch(cfg.HostMode)
+	ctx.PhysicalManager = hostguard.NewManager(ctx.PhysicalManager, hostModes)
 
-	plugin.MustRegister(syncers.NewServiceSyncer(ctx))
-	plugin.MustRegister(syncers.NewSnapshotSyncer(ctx))
-	plugin.MustRegister(syncers.NewSnapshotDataSyncer(ctx))
+	mustRegister(cfg, syncers.NewHostModeController(ctx, hostModes, cfg.HostModeConfigMap), true)
+	mustRegister(cfg, syncers.NewServiceSyncer(ctx), true)
+	// PVCs and snapshots reference the token secret only if it is provisioned
+	tokenSecret := ""
+	if cfg.Enabled(config.TokenSyncerName, false) {
+		tokenSecret = syncers.TokenSecretName
+	}
+	mustRegister(cfg, syncers.NewTokenProvisioner(
+		ctx,
+		pxauth.NewSharedSecretIssuer(cfg.TokenIssuer, []byte(cfg.TokenSharedSecret)),
+		cfg.TokenRoles,
+		cfg.TokenGroups,
+		cfg.TokenLifetime,
+	), false)
+	// the csi bridge fulfills virtual legacy snapshots and the legacy bridge creates host
+	// legacy snapshots, both instead of the volumesnapshot syncer
+	snapshotSyncer := syncers.NewResyncer(syncers.NewSnapshotSyncer(
+		ctx,
+		tokenSecret,
+		cfg.SnapshotPendingTimeout,
+		cfg.SnapshotNamespaceSelector,
+		cfg.MetadataPolicy,
+	), hostModes)
+	csiBridge := syncers.NewCSISnapshotBridge(ctx, cfg.BridgeSnapshotClass, cfg.MetadataPolicy, hostModes)
+	legacyBridge := syncers.NewLegacySnapshotBridge(ctx, cfg.MetadataPolicy, hostModes)
+	if cfg.Enabled(csiBridge.Name(), false) || cfg.Enabled(legacyBridge.Name(), false) {
+		log.New("plugin").Infof("Syncer %s is disabled, because a snapshot bridge is enabled", snapshotSyncer.Name())
+		mustRegister(cfg, syncers.NewCRDGate(
+			cfg,
+			csiBridge,
+			csisnapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshot"),
+		), false)
+		mustRegister(cfg, syncers.NewCRDGate(
+			cfg,
+			legacyBridge,
+			snapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshot"),
+		), false)
+	} else {
+		mustRegister(cfg, syncers.NewCRDGate(
+			cfg,
+			snapshotSyncer,
+			snapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshot"),
+		), true)
+	}
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewResyncer(syncers.NewSnapshotDataSyncer(ctx, cfg.SnapshotNamespaceSelector, cfg.MetadataPolicy), hostModes),
+		snapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshotData"),
+	), true)
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewSnapshotImporter(ctx, cfg.ImportInterval, cfg.MetadataPolicy),
+		snapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshot"),
+	), false)
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewResyncer(syncers.NewSnapshotClassSyncer(ctx, cfg.SnapshotClassAllowlist, cfg.MetadataPolicy), hostModes),
+		csisnapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshotClass"),
+	), false)
+	mustRegister(cfg, syncers.NewPVCHook(ctx, tokenSecret, cfg.PVCPolicy), true)
+	mustRegister(cfg, syncers.NewVolumeStatusReporter(
+		ctx,
+		cfg.PXAPIEndpoint,
+		tokenSecret,
+		cfg.VolumeStatusInterval,
+	), false)
+	mustRegister(cfg, syncers.NewChargebackReporter(
+		ctx,
+		cfg.PXAPIEndpoint,
+		tokenSecret,
+		cfg.ReportInterval,
+	), false)
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewStorageHealthSyncer(ctx, cfg.StorageHealthInterval),
+		pxcorev1.SchemeGroupVersion.WithKind("StorageCluster"),
+	), false)
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewResyncer(syncers.NewAutopilotRuleSyncer(ctx, cfg.AutopilotMaxSize), hostModes),
+		autopilotv1alpha1.SchemeGroupVersion.WithKind("AutopilotRule"),
+	), false)
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewResyncer(syncers.NewStorkRuleSyncer(ctx), hostModes),
+		storkv1alpha1.SchemeGroupVersion.WithKind("Rule"),
+	), false)
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewResyncer(syncers.NewClusterPairSyncer(ctx, cfg.ClusterPairNamespace), hostModes),
+		storkv1alpha1.SchemeGroupVersion.WithKind("ClusterPair"),
+	), false)
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewResyncer(syncers.NewMigrationSyncer(ctx), hostModes),
+		storkv1alpha1.SchemeGroupVersion.WithKind("Migration"),
+	), false)
+	mustRegister(cfg, syncers.NewCRDGate(
+		cfg,
+		syncers.NewResyncer(syncers.NewMigrationScheduleSyncer(ctx), hostModes),
+		storkv1alpha1.SchemeGroupVersion.WithKind("MigrationSchedule"),
+	), false)
+	mustRegister(cfg, syncers.NewCRDSyncer(
+		ctx,
+		snapshotv1.Resource(snapshotv1.VolumeSnapshotResourcePlural).String(),
+		snapshotv1.Resource(snapshotv1.VolumeSnapshotDataResourcePlural).String(),
+	), true)
 
+	if cfg.Enabled(webhook.ServerName, false) {
+		mustRegister(cfg, webhook.NewServer(
+			cfg.WebhookAddress,
+			cfg.WebhookHost,
+			cfg.WebhookFailurePolicy,
+			syncers.NewSnapshotValidator(ctx),
+		), false)
+	} else {
+		log.New("plugin").Infof("Syncer %s is disabled", webhook.ServerName)
+		// webhooks left behind by an earlier run would fail admission with the Fail policy
+		if err := webhook.Unregister(ctx.Context, ctx.VirtualManager.GetConfig()); err != nil {
+			panic(err)
+		}
+	}
+
+	metrics.Start(ctx.Context, cfg.MetricsAddress)
 	plugin.MustStart()
 }
+
+// mustRegister registers the syncer with the plugin unless it is disabled.
+func mustRegister(cfg *config.Config, s syncer.Base, defaultEnabled bool) {
+	if !cfg.Enabled(s.Name(), defaultEnabled) {
+		log.New("plugin").Infof("Syncer %s is disabled", s.Name())
+		return
+	}
+
+	plugin.MustRegister(s)
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = snapshotv1.AddToScheme(scheme)
+	_ = csisnapshotv1.AddToScheme(scheme)
+}
+
+// newClient creates a client for a host or virtual cluster from the given kube config. If
+// the path is empty, the default loading rules ($KUBECONFIG, ~/.kube/config) are used.
+func newClient(kubeConfig string) (client.Client, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeConfig
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		rules,
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "load kube config")
+	}
+
+	k8sClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, errors.Wrap(err, "create client")
+	}
+
+	return k8sClient, nil
+}
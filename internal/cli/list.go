@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+type listOptions struct {
+	kubeConfig string
+	namespace  string
+}
+
+// NewListCmd returns the command which lists all host storage objects belonging to
+// a vcluster.
+func NewListCmd() *cobra.Command {
+	o := &listOptions{}
+	cmd := &cobra.Command{
+		Use:   "list VCLUSTER",
+		Short: "List all host storage objects belonging to a vcluster",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k8sClient, err := newClient(o.kubeConfig)
+			if err != nil {
+				return err
+			}
+
+			rows, err := listOwnedObjects(cmd.Context(), k8sClient, args[0], o.namespace)
+			if err != nil {
+				return err
+			}
+
+			return printRows(cmd.OutOrStdout(), rows)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.kubeConfig, "kubeconfig", "", "Path to the host cluster kube config")
+	cmd.Flags().StringVar(&o.namespace, "namespace", "", "Host namespace of the vcluster")
+	_ = cmd.MarkFlagRequired("namespace")
+	return cmd
+}
+
+// listOwnedObjects returns the host objects of the vcluster in its host namespace. Other
+// vclusters of the same name in other namespaces carry the same labels, so cluster
+// scoped VolumeSnapshotData is only returned if it references a snapshot in namespace.
+func listOwnedObjects(ctx context.Context, k8sClient client.Client, vcluster, namespace string) ([]row, error) {
+	kinds := []struct {
+		kind       string
+		list       client.ObjectList
+		namespaced bool
+	}{
+		{kind: kindVolumeSnapshot, list: &snapshotv1.VolumeSnapshotList{}, namespaced: true},
+		{kind: kindVolumeSnapshotData, list: &snapshotv1.VolumeSnapshotDataList{}},
+		{kind: kindPVC, list: &corev1.PersistentVolumeClaimList{}, namespaced: true},
+	}
+
+	// objects synced before provenance labels were introduced only carry the vcluster marker
+	selectors := []labels.Selector{
+		provenance.Selector(vcluster),
+		labels.SelectorFromSet(labels.Set{translate.MarkerLabel: vcluster}),
+	}
+
+	rows := []row{}
+	for _, k := range kinds {
+		seen := map[types.UID]bool{}
+		for _, selector := range selectors {
+			opts := []client.ListOption{client.MatchingLabelsSelector{Selector: selector}}
+			if k.namespaced {
+				opts = append(opts, client.InNamespace(namespace))
+			}
+			if err := k8sClient.List(ctx, k.list, opts...); err != nil {
+				return nil, errors.Wrapf(err, "list %s", k.kind)
+			}
+
+			objs, err := meta.ExtractList(k.list)
+			if err != nil {
+				return nil, errors.Wrapf(err, "extract %s list", k.kind)
+			}
+
+			for _, o := range objs {
+				obj := o.(client.Object)
+				if seen[obj.GetUID()] {
+					continue
+				}
+				if data, ok := obj.(*snapshotv1.VolumeSnapshotData); ok && !referencesNamespace(data, namespace) {
+					continue
+				}
+				seen[obj.GetUID()] = true
+
+				owner, ok := provenance.FromObject(obj)
+				if !ok {
+					continue
+				}
+				rows = append(rows, row{Kind: k.kind, Object: obj, Owner: owner})
+			}
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,435 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/convert"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+	"github.com/portworx/pxe-vcluster/internal/syncers"
+)
+
+// Migration states of a legacy snapshot, which are persisted in the progress ConfigMap.
+const (
+	migrationSkipped  = "Skipped"
+	migrationFailed   = "Failed"
+	migrationPlanned  = "Planned"
+	migrationCreated  = "Created"
+	migrationVerified = "Verified"
+	migrationRemoved  = "Removed"
+)
+
+type migrateOptions struct {
+	kubeConfig        string
+	hostKubeConfig    string
+	hostNamespace     string
+	snapshotClassName string
+	progressNamespace string
+	progressName      string
+	timeout           time.Duration
+	dryRun            bool
+	deleteLegacy      bool
+}
+
+// migration is the progress of a single legacy snapshot.
+type migration struct {
+	Snapshot   *snapshotv1.VolumeSnapshot
+	Data       *snapshotv1.VolumeSnapshotData
+	SnapshotID string
+	State      string
+	Message    string
+}
+
+// NewMigrateSnapshotsCmd returns the command which migrates the legacy external-storage
+// snapshots of a virtual cluster to pre-provisioned CSI snapshots.
+func NewMigrateSnapshotsCmd() *cobra.Command {
+	o := &migrateOptions{}
+	cmd := &cobra.Command{
+		Use:   "migrate-snapshots",
+		Short: "Migrate legacy Portworx snapshots of a vcluster to CSI snapshots",
+		Long: `Migrate all legacy VolumeSnapshot/VolumeSnapshotData pairs of a virtual cluster to
+pre-provisioned CSI VolumeSnapshotContents and VolumeSnapshots using the Portworx snapshot ID.
+
+The command runs against the virtual cluster. Progress is stored in a ConfigMap, so an
+interrupted migration continues where it stopped when the command is run again.
+
+Deleting the legacy snapshots with --delete-legacy keeps their host objects and Portworx
+snapshots, which the CSI snapshots now reference. The plugin only releases host objects
+approved on the host, so --delete-legacy requires access to the host namespace of the
+vcluster through --host-kubeconfig and --host-namespace.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.deleteLegacy && o.hostNamespace == "" {
+				return errors.New("--host-namespace is required with --delete-legacy")
+			}
+
+			k8sClient, err := newClient(o.kubeConfig)
+			if err != nil {
+				return err
+			}
+
+			var hostClient client.Client
+			if o.deleteLegacy {
+				hostClient, err = newClient(o.hostKubeConfig)
+				if err != nil {
+					return err
+				}
+			}
+
+			migrations, err := o.run(cmd.Context(), k8sClient, hostClient)
+			if migrations != nil {
+				if printErr := printMigrations(cmd.OutOrStdout(), migrations); printErr != nil {
+					return printErr
+				}
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&o.kubeConfig, "kubeconfig", "", "Path to the virtual cluster kube config")
+	cmd.Flags().StringVar(&o.hostKubeConfig, "host-kubeconfig", "", "Path to the host cluster kube config, used with --delete-legacy")
+	cmd.Flags().StringVar(&o.hostNamespace, "host-namespace", "", "Host namespace of the vcluster, required with --delete-legacy")
+	cmd.Flags().StringVar(&o.snapshotClassName, "snapshot-class", "", "VolumeSnapshotClass of the created CSI snapshots")
+	cmd.Flags().StringVar(&o.progressNamespace, "progress-namespace", "kube-system", "Namespace of the progress ConfigMap")
+	cmd.Flags().StringVar(&o.progressName, "progress-name", "pxe-snapshot-migration", "Name of the progress ConfigMap")
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 5*time.Minute, "Time to wait for a CSI snapshot to become ready")
+	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Only report what would be migrated")
+	cmd.Flags().BoolVar(&o.deleteLegacy, "delete-legacy", false, "Delete the legacy snapshots after the CSI snapshots are ready")
+	return cmd
+}
+
+func (o *migrateOptions) run(ctx context.Context, k8sClient, hostClient client.Client) ([]*migration, error) {
+	progress, err := o.loadProgress(ctx, k8sClient)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := listMigrations(ctx, k8sClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range migrations {
+		if m.State == migrationSkipped {
+			continue
+		}
+		if state, ok := progress.Data[progressKey(m.Snapshot)]; ok {
+			m.State = state
+		}
+
+		if o.dryRun {
+			if m.State == "" {
+				m.State = migrationPlanned
+			}
+			continue
+		}
+
+		if err := o.migrate(ctx, k8sClient, hostClient, m); err != nil {
+			m.State, m.Message = migrationFailed, err.Error()
+		}
+		if err := o.saveProgress(ctx, k8sClient, progress, m); err != nil {
+			return migrations, err
+		}
+	}
+
+	return migrations, nil
+}
+
+// migrate moves a legacy snapshot forward until it reaches its final state.
+func (o *migrateOptions) migrate(ctx context.Context, k8sClient, hostClient client.Client, m *migration) error {
+	if m.State == "" || m.State == migrationFailed {
+		if err := o.createCSISnapshot(ctx, k8sClient, m); err != nil {
+			return err
+		}
+		m.State = migrationCreated
+	}
+
+	if m.State == migrationCreated {
+		if err := o.waitForCSISnapshot(ctx, k8sClient, m); err != nil {
+			return err
+		}
+		m.State = migrationVerified
+	}
+
+	if m.State == migrationVerified && o.deleteLegacy {
+		if err := o.deleteLegacySnapshot(ctx, k8sClient, hostClient, m); err != nil {
+			return err
+		}
+		m.State = migrationRemoved
+	}
+
+	return nil
+}
+
+func (o *migrateOptions) createCSISnapshot(ctx context.Context, k8sClient client.Client, m *migration) error {
+	contentName := csiContentName(m.Snapshot)
+	content := &csisnapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: contentName,
+		},
+		Spec: csisnapshotv1.VolumeSnapshotContentSpec{
+			VolumeSnapshotRef: corev1.ObjectReference{
+				Namespace: m.Snapshot.Namespace,
+				Name:      m.Snapshot.Name,
+			},
+			// the Portworx snapshot is still referenced by the legacy snapshot
+			DeletionPolicy: csisnapshotv1.VolumeSnapshotContentRetain,
+			Driver:         snapshotv1.PortworxCsiProvisionerName,
+			Source: csisnapshotv1.VolumeSnapshotContentSource{
+				SnapshotHandle: &m.SnapshotID,
+			},
+		},
+	}
+
+	snapshot := &csisnapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: m.Snapshot.Namespace,
+			Name:      m.Snapshot.Name,
+			Labels:    m.Snapshot.Labels,
+		},
+		Spec: csisnapshotv1.VolumeSnapshotSpec{
+			Source: csisnapshotv1.VolumeSnapshotSource{
+				VolumeSnapshotContentName: &contentName,
+			},
+		},
+	}
+
+	if o.snapshotClassName != "" {
+		content.Spec.VolumeSnapshotClassName = &o.snapshotClassName
+		snapshot.Spec.VolumeSnapshotClassName = &o.snapshotClassName
+	}
+
+	if err := k8sClient.Create(ctx, content); err != nil && !kerrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "create volume snapshot content")
+	}
+	if err := k8sClient.Create(ctx, snapshot); err != nil && !kerrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "create csi volume snapshot")
+	}
+
+	return nil
+}
+
+func (o *migrateOptions) waitForCSISnapshot(ctx context.Context, k8sClient client.Client, m *migration) error {
+	snapshot := &csisnapshotv1.VolumeSnapshot{}
+	err := wait.PollImmediateWithContext(ctx, 2*time.Second, o.timeout, func(ctx context.Context) (bool, error) {
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(m.Snapshot), snapshot); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		if snapshot.Status != nil && snapshot.Status.Error != nil && snapshot.Status.Error.Message != nil {
+			return false, errors.New(*snapshot.Status.Error.Message)
+		}
+		return snapshot.Status != nil && snapshot.Status.ReadyToUse != nil && *snapshot.Status.ReadyToUse, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "wait for csi volume snapshot to become ready")
+	}
+
+	return nil
+}
+
+// deleteLegacySnapshot deletes the virtual legacy objects. The plugin is asked to release
+// their host objects first, as deleting those would delete the Portworx snapshot.
+func (o *migrateOptions) deleteLegacySnapshot(ctx context.Context, k8sClient, hostClient client.Client, m *migration) error {
+	for _, obj := range []client.Object{m.Snapshot, m.Data} {
+		if err := approveRelease(ctx, hostClient, o.hostNamespace, obj); err != nil {
+			return err
+		}
+		if err := releaseAndDelete(ctx, k8sClient, obj, o.timeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// approveRelease approves the release of the host object of the virtual object, which the
+// plugin only honors when it is set on the host object.
+func approveRelease(ctx context.Context, hostClient client.Client, hostNamespace string, obj client.Object) error {
+	var list client.ObjectList
+	switch obj.(type) {
+	case *snapshotv1.VolumeSnapshot:
+		list = &snapshotv1.VolumeSnapshotList{}
+	case *snapshotv1.VolumeSnapshotData:
+		list = &snapshotv1.VolumeSnapshotDataList{}
+	default:
+		return errors.Errorf("unsupported object %T", obj)
+	}
+
+	if err := hostClient.List(ctx, list, client.InNamespace(hostNamespace)); err != nil {
+		return errors.Wrapf(err, "list host objects of %s", obj.GetName())
+	}
+	pObjs, err := meta.ExtractList(list)
+	if err != nil {
+		return errors.Wrapf(err, "extract host objects of %s", obj.GetName())
+	}
+
+	for _, o := range pObjs {
+		pObj := o.(client.Object)
+		owner, ok := provenance.FromObject(pObj)
+		if !ok || owner.Namespace != obj.GetNamespace() || owner.Name != obj.GetName() ||
+			owner.UID != "" && owner.UID != obj.GetUID() {
+			continue
+		}
+
+		patch := client.MergeFrom(pObj.DeepCopyObject().(client.Object))
+		pObj.SetAnnotations(provenance.Merge(pObj.GetAnnotations(), map[string]string{
+			syncers.ReleaseApprovedAnnotation: string(obj.GetUID()),
+		}))
+		if err := hostClient.Patch(ctx, pObj, patch); err != nil {
+			return errors.Wrapf(err, "approve release of host object %s/%s", pObj.GetNamespace(), pObj.GetName())
+		}
+		return nil
+	}
+
+	return errors.Errorf("host object of %s not found in namespace %s", obj.GetName(), hostNamespace)
+}
+
+func releaseAndDelete(ctx context.Context, k8sClient client.Client, obj client.Object, timeout time.Duration) error {
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[syncers.RetainAnnotation] = "true"
+	obj.SetAnnotations(annotations)
+	if err := k8sClient.Patch(ctx, obj, patch); err != nil {
+		return errors.Wrapf(err, "request release of %s", obj.GetName())
+	}
+
+	err := wait.PollImmediateWithContext(ctx, 2*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			return false, err
+		}
+		return obj.GetAnnotations()[syncers.ReleasedAnnotation] == "true", nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "wait for release of %s", obj.GetName())
+	}
+
+	if err := k8sClient.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrapf(err, "delete %s", obj.GetName())
+	}
+
+	return nil
+}
+
+// listMigrations returns all legacy snapshot pairs of the virtual cluster. Snapshots
+// which can't be migrated are returned in the skipped state.
+func listMigrations(ctx context.Context, k8sClient client.Client) ([]*migration, error) {
+	snapshots := &snapshotv1.VolumeSnapshotList{}
+	if err := k8sClient.List(ctx, snapshots); err != nil {
+		return nil, errors.Wrap(err, "list volume snapshots")
+	}
+
+	migrations := []*migration{}
+	for i := range snapshots.Items {
+		m := &migration{Snapshot: &snapshots.Items[i]}
+		migrations = append(migrations, m)
+
+		if !convert.IsLegacySnapshotReady(m.Snapshot.Status) {
+			m.State, m.Message = migrationSkipped, "snapshot is not ready"
+			continue
+		}
+		if m.Snapshot.Spec.SnapshotDataName == "" {
+			m.State, m.Message = migrationSkipped, "snapshot is not bound to a volume snapshot data"
+			continue
+		}
+
+		m.Data = &snapshotv1.VolumeSnapshotData{}
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: m.Snapshot.Spec.SnapshotDataName}, m.Data); err != nil {
+			m.State, m.Message = migrationSkipped, fmt.Sprintf("get volume snapshot data: %v", err)
+			continue
+		}
+
+		source := m.Data.Spec.PortworxSnapshot
+		if source == nil {
+			m.State, m.Message = migrationSkipped, "not a Portworx snapshot"
+			continue
+		}
+		if source.SnapshotType == snapshotv1.PortworxSnapshotTypeCloud {
+			m.State, m.Message = migrationSkipped, "cloud snapshots are not supported"
+			continue
+		}
+		m.SnapshotID = source.SnapshotID
+	}
+
+	return migrations, nil
+}
+
+func (o *migrateOptions) loadProgress(ctx context.Context, k8sClient client.Client) (*corev1.ConfigMap, error) {
+	progress := &corev1.ConfigMap{}
+	err := k8sClient.Get(ctx, client.ObjectKey{Namespace: o.progressNamespace, Name: o.progressName}, progress)
+	if err == nil {
+		if progress.Data == nil {
+			progress.Data = map[string]string{}
+		}
+		return progress, nil
+	} else if !kerrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "get progress config map")
+	}
+
+	progress = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: o.progressNamespace,
+			Name:      o.progressName,
+		},
+		Data: map[string]string{},
+	}
+	if !o.dryRun {
+		if err := k8sClient.Create(ctx, progress); err != nil {
+			return nil, errors.Wrap(err, "create progress config map")
+		}
+	}
+
+	return progress, nil
+}
+
+func (o *migrateOptions) saveProgress(ctx context.Context, k8sClient client.Client, progress *corev1.ConfigMap, m *migration) error {
+	progress.Data[progressKey(m.Snapshot)] = m.State
+	if err := k8sClient.Update(ctx, progress); err != nil {
+		return errors.Wrap(err, "update progress config map")
+	}
+
+	return nil
+}
+
+func progressKey(snapshot *snapshotv1.VolumeSnapshot) string {
+	// names and namespaces can't contain underscores
+	return snapshot.Namespace + "_" + snapshot.Name
+}
+
+func csiContentName(snapshot *snapshotv1.VolumeSnapshot) string {
+	return translate.SafeConcatName("snapcontent-migrated", snapshot.Namespace, snapshot.Name)
+}
+
+func printMigrations(out io.Writer, migrations []*migration) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tSNAPSHOT ID\tSTATE\tMESSAGE")
+	for _, m := range migrations {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\n",
+			m.Snapshot.Namespace,
+			m.Snapshot.Name,
+			valueOrNone(m.SnapshotID),
+			m.State,
+			m.Message,
+		)
+	}
+	return w.Flush()
+}
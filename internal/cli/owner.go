@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+const (
+	kindVolumeSnapshot     = "volumesnapshot"
+	kindVolumeSnapshotData = "volumesnapshotdata"
+	kindPVC                = "pvc"
+	kindPXVolume           = "px-volume"
+	kindPXSnapshot         = "px-snapshot"
+)
+
+type ownerOptions struct {
+	kubeConfig string
+	namespace  string
+}
+
+// NewOwnerCmd returns the command which maps a host storage object back to the
+// vcluster and virtual object it was created for.
+func NewOwnerCmd() *cobra.Command {
+	o := &ownerOptions{}
+	cmd := &cobra.Command{
+		Use:   "owner KIND NAME",
+		Short: "Print the vcluster and virtual object owning a host storage object",
+		Long: `Print the vcluster, virtual namespace and virtual name owning a host storage object.
+
+KIND is one of volumesnapshot, volumesnapshotdata, pvc, px-volume or px-snapshot.
+For px-volume and px-snapshot, NAME is the Portworx volume or snapshot ID.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k8sClient, err := newClient(o.kubeConfig)
+			if err != nil {
+				return err
+			}
+
+			r, err := findOwner(cmd.Context(), k8sClient, strings.ToLower(args[0]), o.namespace, args[1])
+			if err != nil {
+				return err
+			}
+
+			return printRows(cmd.OutOrStdout(), []row{*r})
+		},
+	}
+
+	cmd.Flags().StringVar(&o.kubeConfig, "kubeconfig", "", "Path to the host cluster kube config")
+	cmd.Flags().StringVarP(&o.namespace, "namespace", "n", "", "Host namespace of the object")
+	return cmd
+}
+
+func findOwner(ctx context.Context, k8sClient client.Client, kind, namespace, name string) (*row, error) {
+	var obj client.Object
+	switch kind {
+	case kindVolumeSnapshot:
+		obj = &snapshotv1.VolumeSnapshot{}
+	case kindVolumeSnapshotData:
+		obj = &snapshotv1.VolumeSnapshotData{}
+	case kindPVC:
+		obj = &corev1.PersistentVolumeClaim{}
+	case kindPXVolume:
+		return findPXVolumeOwner(ctx, k8sClient, name)
+	case kindPXSnapshot:
+		return findPXSnapshotOwner(ctx, k8sClient, name)
+	default:
+		return nil, errors.Errorf("unsupported kind %q", kind)
+	}
+
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		return nil, errors.Wrapf(err, "get %s %s", kind, name)
+	}
+
+	return newOwnerRow(kind, obj)
+}
+
+// findPXVolumeOwner resolves a Portworx volume through its PersistentVolume to the
+// host PersistentVolumeClaim carrying the provenance.
+func findPXVolumeOwner(ctx context.Context, k8sClient client.Client, volumeID string) (*row, error) {
+	pvList := &corev1.PersistentVolumeList{}
+	if err := k8sClient.List(ctx, pvList); err != nil {
+		return nil, errors.Wrap(err, "list persistent volumes")
+	}
+
+	for _, pv := range pvList.Items {
+		if !isPXVolume(&pv, volumeID) {
+			continue
+		}
+		if pv.Spec.ClaimRef == nil {
+			return nil, errors.Errorf("persistent volume %s of Portworx volume %s is not bound", pv.Name, volumeID)
+		}
+
+		return findOwner(ctx, k8sClient, kindPVC, pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name)
+	}
+
+	return nil, errors.Errorf("no persistent volume found for Portworx volume %s", volumeID)
+}
+
+func isPXVolume(pv *corev1.PersistentVolume, volumeID string) bool {
+	if pv.Name == volumeID {
+		return true
+	}
+	if pv.Spec.CSI != nil &&
+		snapshotv1.GetSupportedVolumeFromPVSpec(&pv.Spec) == "pxd" &&
+		pv.Spec.CSI.VolumeHandle == volumeID {
+		return true
+	}
+	return pv.Spec.PortworxVolume != nil && pv.Spec.PortworxVolume.VolumeID == volumeID
+}
+
+// findPXSnapshotOwner resolves a Portworx snapshot through its VolumeSnapshotData. If
+// the data was created by the host snapshot controller it carries no provenance, in
+// which case the referenced VolumeSnapshot is used.
+func findPXSnapshotOwner(ctx context.Context, k8sClient client.Client, snapshotID string) (*row, error) {
+	dataList := &snapshotv1.VolumeSnapshotDataList{}
+	if err := k8sClient.List(ctx, dataList); err != nil {
+		return nil, errors.Wrap(err, "list volume snapshot datas")
+	}
+
+	for i := range dataList.Items {
+		data := &dataList.Items[i]
+		if data.Spec.PortworxSnapshot == nil || data.Spec.PortworxSnapshot.SnapshotID != snapshotID {
+			continue
+		}
+		if r, err := newOwnerRow(kindVolumeSnapshotData, data); err == nil {
+			return r, nil
+		}
+		if data.Spec.VolumeSnapshotRef == nil {
+			return nil, errors.Errorf("volume snapshot data %s is not managed by a vcluster", data.Name)
+		}
+
+		// the snapshot controller references snapshots as namespace/name
+		namespace, name := data.Spec.VolumeSnapshotRef.Namespace, data.Spec.VolumeSnapshotRef.Name
+		if parts := strings.SplitN(name, "/", 2); namespace == "" && len(parts) == 2 {
+			namespace, name = parts[0], parts[1]
+		}
+
+		return findOwner(ctx, k8sClient, kindVolumeSnapshot, namespace, name)
+	}
+
+	return nil, errors.Errorf("no volume snapshot data found for Portworx snapshot %s", snapshotID)
+}
+
+func newOwnerRow(kind string, obj client.Object) (*row, error) {
+	owner, ok := provenance.FromObject(obj)
+	if !ok {
+		return nil, errors.Errorf("%s %s is not managed by a vcluster", kind, obj.GetName())
+	}
+
+	return &row{Kind: kind, Object: obj, Owner: owner}, nil
+}
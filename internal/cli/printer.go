@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+// row is a host storage object together with the tenant it belongs to.
+type row struct {
+	Kind   string
+	Object client.Object
+	Owner  provenance.Owner
+}
+
+func printRows(out io.Writer, rows []row) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tVCLUSTER\tVIRTUAL NAMESPACE\tVIRTUAL NAME")
+	for _, r := range rows {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Kind,
+			valueOrNone(r.Object.GetNamespace()),
+			r.Object.GetName(),
+			r.Owner.VCluster,
+			valueOrNone(r.Owner.Namespace),
+			r.Owner.Name,
+		)
+	}
+	return w.Flush()
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,350 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/openstorage"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+// Results of the removal of a host storage artifact.
+const (
+	teardownPlanned  = "Planned"
+	teardownRemoved  = "Removed"
+	teardownRetained = "Retained"
+	teardownSkipped  = "Skipped"
+	teardownFailed   = "Failed"
+)
+
+type teardownOptions struct {
+	kubeConfig string
+	namespace  string
+	pxEndpoint string
+	pxToken    string
+	timeout    time.Duration
+	dryRun     bool
+	force      bool
+}
+
+// artifact is a host storage artifact of a vcluster and the result of its removal.
+type artifact struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Result    string
+	Message   string
+
+	object client.Object
+}
+
+// NewTeardownCmd returns the command which removes the host storage artifacts of a
+// deleted vcluster.
+func NewTeardownCmd() *cobra.Command {
+	o := &teardownOptions{}
+	cmd := &cobra.Command{
+		Use:   "teardown VCLUSTER",
+		Short: "Remove all host snapshots of a deleted vcluster",
+		Long: `Remove the host VolumeSnapshots, VolumeSnapshotData and Portworx snapshots of a vcluster,
+which are left behind when the vcluster is deleted.
+
+Objects are found through the vcluster markers and provenance labels in the host
+namespace of the vcluster given by --namespace; VolumeSnapshotData only if it references
+a snapshot in that namespace. They are removed in dependency order: VolumeSnapshots
+first, so the snapshot controller removes their data and Portworx snapshots, then the
+remaining VolumeSnapshotData and finally the remaining Portworx snapshots carrying the
+provenance labels of the vcluster, if --px-endpoint is set. Objects released through the
+pxe.portworx.io/retain-host-object annotation are kept.
+
+The command refuses to remove anything while the StatefulSet or Service of the vcluster
+still exists in --namespace, because the vcluster would recreate the snapshots. Pass
+--force to remove them anyway, e.g. if only leftovers of the vcluster remain.
+
+The command runs against the host cluster and prints what was removed and what failed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k8sClient, err := newClient(o.kubeConfig)
+			if err != nil {
+				return err
+			}
+
+			artifacts, err := o.run(cmd.Context(), k8sClient, args[0])
+			if artifacts != nil {
+				if printErr := printArtifacts(cmd.OutOrStdout(), artifacts); printErr != nil {
+					return printErr
+				}
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&o.kubeConfig, "kubeconfig", "", "Path to the host cluster kube config")
+	cmd.Flags().StringVar(&o.namespace, "namespace", "", "Host namespace of the vcluster")
+	_ = cmd.MarkFlagRequired("namespace")
+	cmd.Flags().StringVar(&o.pxEndpoint, "px-endpoint", "", "OpenStorage SDK endpoint used to remove leftover Portworx snapshots")
+	cmd.Flags().StringVar(&o.pxToken, "px-token", "", "Token for the OpenStorage SDK on clusters with PX-Security")
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 5*time.Minute, "Time to wait for the snapshot controller to remove snapshots")
+	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Only report what would be removed")
+	cmd.Flags().BoolVar(&o.force, "force", false, "Remove the artifacts even if the vcluster still exists")
+	return cmd
+}
+
+func (o *teardownOptions) run(ctx context.Context, k8sClient client.Client, vcluster string) ([]*artifact, error) {
+	if !o.dryRun && !o.force {
+		if err := checkVClusterDeleted(ctx, k8sClient, vcluster, o.namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshots, datas, pxSnapshots, err := planTeardown(ctx, k8sClient, vcluster, o.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := append(append(snapshots, datas...), pxSnapshots...)
+	if o.dryRun {
+		return artifacts, nil
+	}
+
+	o.deleteObjects(ctx, k8sClient, snapshots)
+	o.deleteObjects(ctx, k8sClient, datas)
+	o.deletePXSnapshots(ctx, vcluster, pxSnapshots)
+
+	failed := 0
+	for _, a := range artifacts {
+		if a.Result == teardownFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return artifacts, errors.Errorf("%d of %d host storage artifacts could not be removed", failed, len(artifacts))
+	}
+
+	return artifacts, nil
+}
+
+// checkVClusterDeleted returns an error if the StatefulSet or Service of the vcluster
+// still exists in namespace.
+func checkVClusterDeleted(ctx context.Context, k8sClient client.Client, vcluster, namespace string) error {
+	key := client.ObjectKey{Namespace: namespace, Name: vcluster}
+	for _, obj := range []client.Object{&appsv1.StatefulSet{}, &corev1.Service{}} {
+		err := k8sClient.Get(ctx, key, obj)
+		if err == nil {
+			return errors.Errorf("vcluster %s still exists in namespace %s, delete it first or pass --force", vcluster, namespace)
+		} else if !kerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "check if vcluster %s exists", vcluster)
+		}
+	}
+
+	return nil
+}
+
+// planTeardown returns the host snapshots of the vcluster in namespace, their data and
+// the Portworx snapshots backing the data. Data created by the host snapshot controller
+// carries no vcluster labels and is found through the snapshot it references.
+func planTeardown(ctx context.Context, k8sClient client.Client, vcluster, namespace string) ([]*artifact, []*artifact, []*artifact, error) {
+	rows, err := listOwnedObjects(ctx, k8sClient, vcluster, namespace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	snapshots := []*artifact{}
+	datas := []*artifact{}
+	ownedSnapshots := map[string]bool{}
+	ownedDatas := map[string]bool{}
+	for _, r := range rows {
+		a := newArtifact(r.Kind, r.Object)
+		if r.Object.GetLabels()[translate.MarkerLabel] != vcluster {
+			a.Result, a.Message = teardownRetained, "released from the vcluster"
+		}
+
+		switch r.Kind {
+		case kindVolumeSnapshot:
+			snapshots = append(snapshots, a)
+			if a.Result == teardownPlanned {
+				ownedSnapshots[a.Namespace+"/"+a.Name] = true
+			}
+		case kindVolumeSnapshotData:
+			datas = append(datas, a)
+			ownedDatas[a.Name] = true
+		}
+	}
+
+	dataList := &snapshotv1.VolumeSnapshotDataList{}
+	if err := k8sClient.List(ctx, dataList); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "list volume snapshot datas")
+	}
+	for i := range dataList.Items {
+		data := &dataList.Items[i]
+		if !ownedDatas[data.Name] && ownedSnapshots[snapshotRef(data)] {
+			datas = append(datas, newArtifact(kindVolumeSnapshotData, data))
+		}
+	}
+
+	pxSnapshots := []*artifact{}
+	for _, a := range datas {
+		data := a.object.(*snapshotv1.VolumeSnapshotData)
+		if a.Result != teardownPlanned || data.Spec.PortworxSnapshot == nil || data.Spec.PortworxSnapshot.SnapshotID == "" {
+			continue
+		}
+		pxSnapshots = append(pxSnapshots, &artifact{
+			Kind:   kindPXSnapshot,
+			Name:   data.Spec.PortworxSnapshot.SnapshotID,
+			Result: teardownPlanned,
+		})
+	}
+
+	return snapshots, datas, pxSnapshots, nil
+}
+
+// deleteObjects deletes the planned objects and waits until they are gone, which gives
+// the snapshot controller the chance to clean up after them.
+func (o *teardownOptions) deleteObjects(ctx context.Context, k8sClient client.Client, artifacts []*artifact) {
+	deleted := []*artifact{}
+	for _, a := range artifacts {
+		if a.Result != teardownPlanned {
+			continue
+		}
+		if err := k8sClient.Delete(ctx, a.object); err != nil && !kerrors.IsNotFound(err) {
+			a.Result, a.Message = teardownFailed, err.Error()
+			continue
+		}
+		deleted = append(deleted, a)
+	}
+
+	_ = wait.PollImmediateWithContext(ctx, 2*time.Second, o.timeout, func(ctx context.Context) (bool, error) {
+		done := true
+		for _, a := range deleted {
+			if a.Result != teardownPlanned {
+				continue
+			}
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(a.object), a.object.DeepCopyObject().(client.Object))
+			if kerrors.IsNotFound(err) {
+				a.Result = teardownRemoved
+				continue
+			}
+			done = false
+		}
+		return done, nil
+	})
+
+	for _, a := range deleted {
+		if a.Result == teardownPlanned {
+			a.Result = teardownFailed
+			a.Message = fmt.Sprintf("still present after %s, check its finalizers", o.timeout)
+		}
+	}
+}
+
+// deletePXSnapshots deletes the Portworx snapshots, which were not removed together with
+// their data. Snapshots without the provenance labels of the vcluster are skipped.
+func (o *teardownOptions) deletePXSnapshots(ctx context.Context, vcluster string, artifacts []*artifact) {
+	if len(artifacts) == 0 {
+		return
+	}
+	if o.pxEndpoint == "" {
+		for _, a := range artifacts {
+			a.Result, a.Message = teardownSkipped, "--px-endpoint is not set"
+		}
+		return
+	}
+
+	sdk, err := openstorage.NewClient(openstorage.Options{
+		Endpoint: o.pxEndpoint,
+		Token:    openstorage.StaticToken(o.pxToken),
+	})
+	if err != nil {
+		for _, a := range artifacts {
+			a.Result, a.Message = teardownFailed, err.Error()
+		}
+		return
+	}
+	defer sdk.Close()
+
+	for _, a := range artifacts {
+		volume, err := sdk.InspectVolume(ctx, a.Name)
+		if openstorage.IsNotFound(err) {
+			a.Result, a.Message = teardownRemoved, "removed by the snapshot controller"
+			continue
+		} else if err != nil {
+			a.Result, a.Message = teardownFailed, err.Error()
+			continue
+		}
+
+		// Stork copies the labels of the host snapshot to the Portworx snapshot
+		owner, _ := provenance.FromObject(&metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{Labels: volume.Locator.VolumeLabels},
+		})
+		if owner.VCluster != vcluster {
+			a.Result, a.Message = teardownSkipped, "Portworx snapshot has no provenance labels of vcluster "+vcluster
+			continue
+		}
+
+		if err := sdk.DeleteVolume(ctx, a.Name); err != nil && !openstorage.IsNotFound(err) {
+			a.Result, a.Message = teardownFailed, err.Error()
+			continue
+		}
+		a.Result = teardownRemoved
+	}
+}
+
+func newArtifact(kind string, obj client.Object) *artifact {
+	return &artifact{
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Result:    teardownPlanned,
+		object:    obj,
+	}
+}
+
+// referencesNamespace returns true if the data references a snapshot in namespace.
+func referencesNamespace(data *snapshotv1.VolumeSnapshotData, namespace string) bool {
+	return strings.HasPrefix(snapshotRef(data), namespace+"/")
+}
+
+// snapshotRef returns the namespace/name of the snapshot referenced by the data.
+func snapshotRef(data *snapshotv1.VolumeSnapshotData) string {
+	if data.Spec.VolumeSnapshotRef == nil {
+		return ""
+	}
+
+	// the snapshot controller references snapshots as namespace/name
+	namespace, name := data.Spec.VolumeSnapshotRef.Namespace, data.Spec.VolumeSnapshotRef.Name
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+func printArtifacts(out io.Writer, artifacts []*artifact) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tRESULT\tMESSAGE")
+	for _, a := range artifacts {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\n",
+			a.Kind,
+			valueOrNone(a.Namespace),
+			a.Name,
+			a.Result,
+			a.Message,
+		)
+	}
+	return w.Flush()
+}
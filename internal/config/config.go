@@ -0,0 +1,432 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/portworx/pxe-vcluster/internal/hostguard"
+	"github.com/portworx/pxe-vcluster/internal/policy"
+)
+
+// The plugin is configured through the environment of its container, which can be set
+// through the env section of the plugin in the vcluster values.
+const (
+	// EnvSync is a comma separated list of syncers to enable ("name") or disable ("-name").
+	EnvSync = "PXE_SYNC"
+	// EnvPaused pauses the plugin if "true": no host objects are created, updated or
+	// deleted until it is unset.
+	EnvPaused = "PXE_PAUSED"
+	// EnvDryRun runs the plugin in dry-run mode if "true": writes to the host are logged
+	// as diff instead of being performed.
+	EnvDryRun = "PXE_DRY_RUN"
+	// EnvHostModeConfigMap is the name of the ConfigMap in the host namespace of the
+	// vcluster whose "mode" key switches between active, paused and dry-run mode at
+	// runtime, overriding EnvPaused and EnvDryRun.
+	EnvHostModeConfigMap = "PXE_HOST_MODE_CONFIGMAP"
+	// EnvMissingCRDPolicy defines what happens if a CRD a syncer depends on is missing.
+	EnvMissingCRDPolicy = "PXE_MISSING_CRD_POLICY"
+	// EnvCRDPollInterval is the interval used to check for missing CRDs on the host.
+	EnvCRDPollInterval = "PXE_CRD_POLL_INTERVAL"
+	// EnvImportInterval is the interval in which pre-existing host snapshots are imported.
+	EnvImportInterval = "PXE_IMPORT_INTERVAL"
+	// EnvSnapshotClassAllowlist is a comma separated list of host VolumeSnapshotClasses
+	// which may be imported. If empty, all Portworx classes are imported.
+	EnvSnapshotClassAllowlist = "PXE_SNAPSHOT_CLASS_ALLOWLIST"
+	// EnvBridgeSnapshotClass is the host VolumeSnapshotClass used for CSI snapshots created
+	// by the snapshot bridge. If empty, the default class of the host is used.
+	EnvBridgeSnapshotClass = "PXE_BRIDGE_SNAPSHOT_CLASS"
+	// EnvTokenSharedSecret is the shared secret Portworx security is configured with. It is
+	// required by the "px-security-token" syncer.
+	EnvTokenSharedSecret = "PXE_TOKEN_SHARED_SECRET"
+	// EnvTokenIssuer is the issuer of minted Portworx tokens.
+	EnvTokenIssuer = "PXE_TOKEN_ISSUER"
+	// EnvTokenRoles is a comma separated list of Portworx roles granted to the vcluster.
+	EnvTokenRoles = "PXE_TOKEN_ROLES"
+	// EnvTokenGroups is a comma separated list of Portworx groups of the vcluster. If
+	// empty, the vcluster name is used as group.
+	EnvTokenGroups = "PXE_TOKEN_GROUPS"
+	// EnvTokenLifetime is the lifetime of minted Portworx tokens.
+	EnvTokenLifetime = "PXE_TOKEN_LIFETIME"
+	// EnvPXAPIEndpoint is the gRPC endpoint of the OpenStorage SDK served by Portworx.
+	EnvPXAPIEndpoint = "PXE_PX_API_ENDPOINT"
+	// EnvSnapshotNamespaceSelector is a label selector for the virtual namespaces whose
+	// snapshots are synced. If empty, snapshots of all namespaces are synced.
+	EnvSnapshotNamespaceSelector = "PXE_SNAPSHOT_NAMESPACE_SELECTOR"
+	// EnvSnapshotPendingTimeout is the time after which virtual snapshots which are not
+	// ready get an error condition. "0" disables the timeout.
+	EnvSnapshotPendingTimeout = "PXE_SNAPSHOT_PENDING_TIMEOUT"
+	// EnvVolumeStatusInterval is the interval in which the status of Portworx volumes is
+	// published on virtual PVCs.
+	EnvVolumeStatusInterval = "PXE_VOLUME_STATUS_INTERVAL"
+	// EnvStorageHealthInterval is the interval in which StorageClusters and StorageNodes
+	// are imported.
+	EnvStorageHealthInterval = "PXE_STORAGE_HEALTH_INTERVAL"
+	// EnvReportInterval is the interval in which the chargeback report is written.
+	EnvReportInterval = "PXE_REPORT_INTERVAL"
+	// EnvPVCPolicy is the storage policy applied to tenant PVCs in YAML or JSON.
+	EnvPVCPolicy = "PXE_PVC_POLICY"
+	// EnvMetadataPolicy filters the labels and annotations propagated per kind and
+	// restricts Stork and Portworx snapshot annotations, in YAML or JSON.
+	EnvMetadataPolicy = "PXE_METADATA_POLICY"
+	// EnvAutopilotMaxSize is the size tenant AutopilotRules may grow volumes to at most.
+	EnvAutopilotMaxSize = "PXE_AUTOPILOT_MAX_SIZE"
+	// EnvClusterPairNamespace is the host namespace holding the ClusterPairs of the admin,
+	// which tenant ClusterPairs reference by name. It is required by the "clusterpair"
+	// syncer.
+	EnvClusterPairNamespace = "PXE_CLUSTER_PAIR_NAMESPACE"
+	// EnvWebhookAddress is the address the admission webhooks are served on.
+	EnvWebhookAddress = "PXE_WEBHOOK_ADDRESS"
+	// EnvWebhookHost is the host the virtual API server reaches the admission webhooks
+	// at. It defaults to the host of the webhook address or 127.0.0.1, which requires the
+	// API server to run in the pod of the plugin.
+	EnvWebhookHost = "PXE_WEBHOOK_HOST"
+	// EnvWebhookFailurePolicy is the failure policy of the admission webhooks, either
+	// "Fail" or "Ignore".
+	EnvWebhookFailurePolicy = "PXE_WEBHOOK_FAILURE_POLICY"
+	// EnvMetricsAddress is the address metrics are served on, "0" disables metrics.
+	EnvMetricsAddress = "PXE_METRICS_ADDRESS"
+)
+
+const (
+	// TokenSyncerName is the name of the syncer provisioning Portworx tokens.
+	TokenSyncerName = "px-security-token"
+	// ClusterPairSyncerName is the name of the syncer for Stork ClusterPairs.
+	ClusterPairSyncerName = "clusterpair"
+)
+
+// MissingCRDPolicy defines how syncers behave when the host cluster lacks their CRD.
+type MissingCRDPolicy string
+
+const (
+	// MissingCRDPolicyFail fails the plugin if a CRD is missing.
+	MissingCRDPolicyFail MissingCRDPolicy = "fail"
+	// MissingCRDPolicyWait disables the syncer until its CRD shows up on the host.
+	MissingCRDPolicyWait MissingCRDPolicy = "wait"
+)
+
+const (
+	defaultCRDPollInterval = time.Minute
+	defaultImportInterval  = 5 * time.Minute
+	defaultTokenIssuer     = "operator.portworx.io"
+	defaultTokenRole       = "system.user"
+	defaultTokenLifetime   = 24 * time.Hour
+	defaultStatusInterval  = time.Minute
+	defaultMetricsAddress  = ":9102"
+	defaultReportInterval  = 15 * time.Minute
+	defaultHealthInterval  = time.Minute
+	defaultWebhookAddress  = "127.0.0.1:9443"
+	defaultPendingTimeout  = 10 * time.Minute
+	defaultPXAPIEndpoint   = "portworx-api.kube-system.svc:9020"
+	defaultHostModeMap     = "pxe-vcluster-mode"
+)
+
+// Config is the configuration of the plugin.
+type Config struct {
+	// HostMode defines if writes to the host cluster are performed, skipped or logged
+	HostMode hostguard.Mode
+
+	// HostModeConfigMap is the host ConfigMap switching the host mode at runtime
+	HostModeConfigMap string
+
+	// MissingCRDPolicy is the policy applied to syncers whose CRD is missing on the host
+	MissingCRDPolicy MissingCRDPolicy
+
+	// CRDPollInterval is the interval in which missing CRDs are checked for
+	CRDPollInterval time.Duration
+
+	// ImportInterval is the interval in which pre-existing host snapshots are imported
+	ImportInterval time.Duration
+
+	// SnapshotClassAllowlist are the host VolumeSnapshotClasses which may be imported
+	SnapshotClassAllowlist []string
+
+	// BridgeSnapshotClass is the host VolumeSnapshotClass used by the snapshot bridge
+	BridgeSnapshotClass string
+
+	// TokenSharedSecret is the shared secret used to sign Portworx tokens
+	TokenSharedSecret string
+
+	// TokenIssuer is the issuer of Portworx tokens
+	TokenIssuer string
+
+	// TokenRoles are the Portworx roles granted to the vcluster
+	TokenRoles []string
+
+	// TokenGroups are the Portworx groups of the vcluster
+	TokenGroups []string
+
+	// TokenLifetime is the lifetime of Portworx tokens
+	TokenLifetime time.Duration
+
+	// PXAPIEndpoint is the gRPC endpoint of the OpenStorage SDK
+	PXAPIEndpoint string
+
+	// SnapshotNamespaceSelector selects the virtual namespaces whose snapshots are synced
+	SnapshotNamespaceSelector labels.Selector
+
+	// SnapshotPendingTimeout is the time after which pending snapshots get an error
+	// condition, zero if disabled
+	SnapshotPendingTimeout time.Duration
+
+	// VolumeStatusInterval is the interval in which volume status is published
+	VolumeStatusInterval time.Duration
+
+	// StorageHealthInterval is the interval in which storage health is imported
+	StorageHealthInterval time.Duration
+
+	// ReportInterval is the interval in which the chargeback report is written
+	ReportInterval time.Duration
+
+	// PVCPolicy is the storage policy applied to tenant PVCs, nil if not configured
+	PVCPolicy *policy.PVCPolicy
+
+	// MetadataPolicy filters propagated metadata, nil if not configured
+	MetadataPolicy *policy.MetadataPolicy
+
+	// AutopilotMaxSize caps volume growth by tenant AutopilotRules, zero if unlimited
+	AutopilotMaxSize resource.Quantity
+
+	// ClusterPairNamespace is the host namespace of the ClusterPairs of the admin
+	ClusterPairNamespace string
+
+	// WebhookAddress is the address admission webhooks are served on
+	WebhookAddress string
+
+	// WebhookHost is the host the virtual API server reaches the admission webhooks at
+	WebhookHost string
+
+	// WebhookFailurePolicy is the failure policy of the admission webhooks
+	WebhookFailurePolicy admissionregistrationv1.FailurePolicyType
+
+	// MetricsAddress is the address metrics are served on
+	MetricsAddress string
+
+	syncers map[string]bool
+}
+
+// MustLoad loads the configuration from the environment and panics on error.
+func MustLoad() *Config {
+	cfg, err := Load()
+	if err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
+
+// Load loads the configuration from the environment.
+func Load() (*Config, error) {
+	cfg := &Config{
+		MissingCRDPolicy: MissingCRDPolicyFail,
+		CRDPollInterval:  defaultCRDPollInterval,
+		ImportInterval:   defaultImportInterval,
+		syncers:          parseSyncers(os.Getenv(EnvSync)),
+
+		HostModeConfigMap: os.Getenv(EnvHostModeConfigMap),
+
+		SnapshotClassAllowlist: parseList(os.Getenv(EnvSnapshotClassAllowlist)),
+		BridgeSnapshotClass:    os.Getenv(EnvBridgeSnapshotClass),
+
+		TokenSharedSecret: os.Getenv(EnvTokenSharedSecret),
+		TokenIssuer:       os.Getenv(EnvTokenIssuer),
+		TokenRoles:        parseList(os.Getenv(EnvTokenRoles)),
+		TokenGroups:       parseList(os.Getenv(EnvTokenGroups)),
+
+		PXAPIEndpoint:        os.Getenv(EnvPXAPIEndpoint),
+		ClusterPairNamespace: os.Getenv(EnvClusterPairNamespace),
+		WebhookAddress:       os.Getenv(EnvWebhookAddress),
+		WebhookHost:          os.Getenv(EnvWebhookHost),
+		WebhookFailurePolicy: admissionregistrationv1.Fail,
+		MetricsAddress:       os.Getenv(EnvMetricsAddress),
+	}
+	if cfg.HostModeConfigMap == "" {
+		cfg.HostModeConfigMap = defaultHostModeMap
+	}
+	if cfg.PXAPIEndpoint == "" {
+		cfg.PXAPIEndpoint = defaultPXAPIEndpoint
+	}
+	if cfg.WebhookAddress == "" {
+		cfg.WebhookAddress = defaultWebhookAddress
+	}
+	if cfg.MetricsAddress == "" {
+		cfg.MetricsAddress = defaultMetricsAddress
+	}
+	if cfg.TokenIssuer == "" {
+		cfg.TokenIssuer = defaultTokenIssuer
+	}
+	if len(cfg.TokenRoles) == 0 {
+		cfg.TokenRoles = []string{defaultTokenRole}
+	}
+	if value := os.Getenv(EnvPVCPolicy); strings.TrimSpace(value) != "" {
+		pvcPolicy, err := policy.ParsePVCPolicy([]byte(value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse %s", EnvPVCPolicy)
+		}
+		cfg.PVCPolicy = pvcPolicy
+	}
+	for _, env := range []struct {
+		name string
+		mode hostguard.Mode
+	}{
+		// pause wins over dry-run
+		{name: EnvDryRun, mode: hostguard.ModeDryRun},
+		{name: EnvPaused, mode: hostguard.ModePaused},
+	} {
+		if value := os.Getenv(env.name); value != "" {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse %s", env.name)
+			}
+			if enabled {
+				cfg.HostMode = env.mode
+			}
+		}
+	}
+	if value := os.Getenv(EnvMetadataPolicy); strings.TrimSpace(value) != "" {
+		metadataPolicy, err := policy.ParseMetadataPolicy([]byte(value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse %s", EnvMetadataPolicy)
+		}
+		cfg.MetadataPolicy = metadataPolicy
+	}
+	if value := os.Getenv(EnvSnapshotNamespaceSelector); value != "" {
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse %s", EnvSnapshotNamespaceSelector)
+		}
+		cfg.SnapshotNamespaceSelector = selector
+	}
+	if value := os.Getenv(EnvAutopilotMaxSize); value != "" {
+		maxSize, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse %s", EnvAutopilotMaxSize)
+		}
+		cfg.AutopilotMaxSize = maxSize
+	}
+	if cfg.Enabled(TokenSyncerName, false) && cfg.TokenSharedSecret == "" {
+		return nil, errors.Errorf("%s is required by syncer %s", EnvTokenSharedSecret, TokenSyncerName)
+	}
+	if cfg.Enabled(ClusterPairSyncerName, false) && cfg.ClusterPairNamespace == "" {
+		return nil, errors.Errorf("%s is required by syncer %s", EnvClusterPairNamespace, ClusterPairSyncerName)
+	}
+
+	if policy := os.Getenv(EnvWebhookFailurePolicy); policy != "" {
+		switch admissionregistrationv1.FailurePolicyType(policy) {
+		case admissionregistrationv1.Fail, admissionregistrationv1.Ignore:
+			cfg.WebhookFailurePolicy = admissionregistrationv1.FailurePolicyType(policy)
+		default:
+			return nil, errors.Errorf("invalid %s %q", EnvWebhookFailurePolicy, policy)
+		}
+	}
+
+	if policy := os.Getenv(EnvMissingCRDPolicy); policy != "" {
+		switch MissingCRDPolicy(policy) {
+		case MissingCRDPolicyFail, MissingCRDPolicyWait:
+			cfg.MissingCRDPolicy = MissingCRDPolicy(policy)
+		default:
+			return nil, errors.Errorf("invalid %s %q", EnvMissingCRDPolicy, policy)
+		}
+	}
+
+	interval, err := durationFromEnv(EnvCRDPollInterval, defaultCRDPollInterval)
+	if err != nil {
+		return nil, err
+	}
+	cfg.CRDPollInterval = interval
+
+	interval, err = durationFromEnv(EnvImportInterval, defaultImportInterval)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ImportInterval = interval
+
+	interval, err = durationFromEnv(EnvTokenLifetime, defaultTokenLifetime)
+	if err != nil {
+		return nil, err
+	}
+	cfg.TokenLifetime = interval
+
+	if os.Getenv(EnvSnapshotPendingTimeout) != "0" {
+		timeout, err := durationFromEnv(EnvSnapshotPendingTimeout, defaultPendingTimeout)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SnapshotPendingTimeout = timeout
+	}
+
+	interval, err = durationFromEnv(EnvVolumeStatusInterval, defaultStatusInterval)
+	if err != nil {
+		return nil, err
+	}
+	cfg.VolumeStatusInterval = interval
+
+	interval, err = durationFromEnv(EnvReportInterval, defaultReportInterval)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ReportInterval = interval
+
+	interval, err = durationFromEnv(EnvStorageHealthInterval, defaultHealthInterval)
+	if err != nil {
+		return nil, err
+	}
+	cfg.StorageHealthInterval = interval
+
+	return cfg, nil
+}
+
+// Enabled returns if the syncer with the given name is enabled. Syncers not mentioned
+// in the configuration fall back to defaultEnabled.
+func (c *Config) Enabled(name string, defaultEnabled bool) bool {
+	if enabled, ok := c.syncers[name]; ok {
+		return enabled
+	}
+	return defaultEnabled
+}
+
+func parseSyncers(value string) map[string]bool {
+	syncers := map[string]bool{}
+	for _, name := range parseList(value) {
+		if strings.HasPrefix(name, "-") {
+			syncers[strings.TrimPrefix(name, "-")] = false
+		} else {
+			syncers[name] = true
+		}
+	}
+	return syncers
+}
+
+func parseList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func durationFromEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse %s", name)
+	}
+	if d <= 0 {
+		return 0, errors.Errorf("%s must be positive", name)
+	}
+
+	return d, nil
+}
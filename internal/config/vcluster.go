@@ -0,0 +1,39 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/loft-sh/vcluster-sdk/plugin/remote"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// syncerAddress is the address the vcluster syncer serves the plugin context on, the
+// default of the vcluster-sdk.
+const syncerAddress = "localhost:10099"
+
+// MultiNamespaceMode returns true if the vcluster syncs every virtual namespace into a
+// host namespace of its own. The vcluster-sdk only knows the single target namespace and
+// drops the option, so it is read from the plugin context of the syncer again.
+func MultiNamespaceMode(ctx context.Context) (bool, error) {
+	conn, err := grpc.DialContext(ctx, syncerAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return false, errors.Wrap(err, "dial vcluster syncer")
+	}
+	defer conn.Close()
+
+	pluginContext, err := remote.NewVClusterClient(conn).GetContext(ctx, &remote.Empty{})
+	if err != nil {
+		return false, errors.Wrap(err, "get plugin context")
+	}
+	options := struct {
+		MultiNamespaceMode bool `json:"multiNamespaceMode,omitempty"`
+	}{}
+	if err := json.Unmarshal([]byte(pluginContext.Options), &options); err != nil {
+		return false, errors.Wrap(err, "unmarshal vcluster options")
+	}
+
+	return options.MultiNamespaceMode, nil
+}
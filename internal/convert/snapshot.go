@@ -0,0 +1,98 @@
+package convert
+
+import (
+	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+)
+
+// LegacySnapshotStatus converts the status of a CSI VolumeSnapshot into the status of a
+// legacy external-storage VolumeSnapshot. A snapshot which is ready to use is reported
+// with a Ready condition, a failed one with an Error condition and everything else as
+// Pending.
+func LegacySnapshotStatus(status *csisnapshotv1.VolumeSnapshotStatus) snapshotv1.VolumeSnapshotStatus {
+	ret := snapshotv1.VolumeSnapshotStatus{
+		Conditions: []snapshotv1.VolumeSnapshotCondition{},
+	}
+	if status == nil {
+		return ret
+	}
+
+	condition := snapshotv1.VolumeSnapshotCondition{
+		Type:   snapshotv1.VolumeSnapshotConditionPending,
+		Status: corev1.ConditionUnknown,
+	}
+	if status.CreationTime != nil {
+		ret.CreationTimestamp = *status.CreationTime
+		condition.Status = corev1.ConditionTrue
+		condition.LastTransitionTime = *status.CreationTime
+	}
+
+	switch {
+	case status.Error != nil:
+		condition.Type = snapshotv1.VolumeSnapshotConditionError
+		condition.Status = corev1.ConditionTrue
+		if status.Error.Time != nil {
+			condition.LastTransitionTime = *status.Error.Time
+		}
+		if status.Error.Message != nil {
+			condition.Message = *status.Error.Message
+		}
+	case status.ReadyToUse != nil && *status.ReadyToUse:
+		condition.Type = snapshotv1.VolumeSnapshotConditionReady
+		condition.Status = corev1.ConditionTrue
+		condition.Message = "Snapshot created successfully and it is ready"
+	}
+
+	ret.Conditions = append(ret.Conditions, condition)
+	return ret
+}
+
+// CSISnapshotStatus converts the status of a legacy external-storage VolumeSnapshot into
+// the status of a CSI VolumeSnapshot. Only the latest condition is taken into account.
+func CSISnapshotStatus(status snapshotv1.VolumeSnapshotStatus) *csisnapshotv1.VolumeSnapshotStatus {
+	readyToUse := false
+	ret := &csisnapshotv1.VolumeSnapshotStatus{
+		ReadyToUse: &readyToUse,
+	}
+	if !status.CreationTimestamp.IsZero() {
+		creationTime := status.CreationTimestamp
+		ret.CreationTime = &creationTime
+	}
+
+	condition := LatestSnapshotCondition(status)
+	if condition == nil || condition.Status != corev1.ConditionTrue {
+		return ret
+	}
+
+	switch condition.Type {
+	case snapshotv1.VolumeSnapshotConditionReady:
+		readyToUse = true
+	case snapshotv1.VolumeSnapshotConditionError:
+		lastTransitionTime, message := condition.LastTransitionTime, condition.Message
+		ret.Error = &csisnapshotv1.VolumeSnapshotError{
+			Time:    &lastTransitionTime,
+			Message: &message,
+		}
+	}
+
+	return ret
+}
+
+// LatestSnapshotCondition returns the last condition of the legacy snapshot status, which
+// is the one appended latest by the snapshot controller, or nil.
+func LatestSnapshotCondition(status snapshotv1.VolumeSnapshotStatus) *snapshotv1.VolumeSnapshotCondition {
+	if len(status.Conditions) == 0 {
+		return nil
+	}
+	return &status.Conditions[len(status.Conditions)-1]
+}
+
+// IsLegacySnapshotReady returns true if the latest condition of the legacy snapshot is Ready.
+func IsLegacySnapshotReady(status snapshotv1.VolumeSnapshotStatus) bool {
+	condition := LatestSnapshotCondition(status)
+	return condition != nil &&
+		condition.Type == snapshotv1.VolumeSnapshotConditionReady &&
+		condition.Status == corev1.ConditionTrue
+}
@@ -0,0 +1,162 @@
+package convert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+)
+
+var (
+	created = metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	failed  = metav1.NewTime(time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC))
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestLegacySnapshotStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   *csisnapshotv1.VolumeSnapshotStatus
+		expected snapshotv1.VolumeSnapshotStatus
+	}{
+		{
+			name:     "no status",
+			expected: snapshotv1.VolumeSnapshotStatus{Conditions: []snapshotv1.VolumeSnapshotCondition{}},
+		},
+		{
+			name:   "pending",
+			status: &csisnapshotv1.VolumeSnapshotStatus{ReadyToUse: boolPtr(false)},
+			expected: snapshotv1.VolumeSnapshotStatus{Conditions: []snapshotv1.VolumeSnapshotCondition{{
+				Type:   snapshotv1.VolumeSnapshotConditionPending,
+				Status: corev1.ConditionUnknown,
+			}}},
+		},
+		{
+			name:   "created but not ready",
+			status: &csisnapshotv1.VolumeSnapshotStatus{CreationTime: &created, ReadyToUse: boolPtr(false)},
+			expected: snapshotv1.VolumeSnapshotStatus{
+				CreationTimestamp: created,
+				Conditions: []snapshotv1.VolumeSnapshotCondition{{
+					Type:               snapshotv1.VolumeSnapshotConditionPending,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: created,
+				}},
+			},
+		},
+		{
+			name:   "ready",
+			status: &csisnapshotv1.VolumeSnapshotStatus{CreationTime: &created, ReadyToUse: boolPtr(true)},
+			expected: snapshotv1.VolumeSnapshotStatus{
+				CreationTimestamp: created,
+				Conditions: []snapshotv1.VolumeSnapshotCondition{{
+					Type:               snapshotv1.VolumeSnapshotConditionReady,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: created,
+					Message:            "Snapshot created successfully and it is ready",
+				}},
+			},
+		},
+		{
+			name: "error",
+			status: &csisnapshotv1.VolumeSnapshotStatus{
+				ReadyToUse: boolPtr(true),
+				Error:      &csisnapshotv1.VolumeSnapshotError{Time: &failed, Message: stringPtr("volume not found")},
+			},
+			expected: snapshotv1.VolumeSnapshotStatus{Conditions: []snapshotv1.VolumeSnapshotCondition{{
+				Type:               snapshotv1.VolumeSnapshotConditionError,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: failed,
+				Message:            "volume not found",
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := LegacySnapshotStatus(tt.status)
+			if diff := cmp.Diff(tt.expected, status); diff != "" {
+				t.Errorf("unexpected status (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCSISnapshotStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   snapshotv1.VolumeSnapshotStatus
+		expected *csisnapshotv1.VolumeSnapshotStatus
+	}{
+		{
+			name:     "no conditions",
+			expected: &csisnapshotv1.VolumeSnapshotStatus{ReadyToUse: boolPtr(false)},
+		},
+		{
+			name: "pending",
+			status: snapshotv1.VolumeSnapshotStatus{
+				CreationTimestamp: created,
+				Conditions: []snapshotv1.VolumeSnapshotCondition{{
+					Type:   snapshotv1.VolumeSnapshotConditionPending,
+					Status: corev1.ConditionTrue,
+				}},
+			},
+			expected: &csisnapshotv1.VolumeSnapshotStatus{CreationTime: &created, ReadyToUse: boolPtr(false)},
+		},
+		{
+			name: "ready after pending",
+			status: snapshotv1.VolumeSnapshotStatus{
+				CreationTimestamp: created,
+				Conditions: []snapshotv1.VolumeSnapshotCondition{
+					{Type: snapshotv1.VolumeSnapshotConditionPending, Status: corev1.ConditionTrue},
+					{Type: snapshotv1.VolumeSnapshotConditionReady, Status: corev1.ConditionTrue},
+				},
+			},
+			expected: &csisnapshotv1.VolumeSnapshotStatus{CreationTime: &created, ReadyToUse: boolPtr(true)},
+		},
+		{
+			name: "ready condition not true",
+			status: snapshotv1.VolumeSnapshotStatus{Conditions: []snapshotv1.VolumeSnapshotCondition{{
+				Type:   snapshotv1.VolumeSnapshotConditionReady,
+				Status: corev1.ConditionFalse,
+			}}},
+			expected: &csisnapshotv1.VolumeSnapshotStatus{ReadyToUse: boolPtr(false)},
+		},
+		{
+			name: "error",
+			status: snapshotv1.VolumeSnapshotStatus{Conditions: []snapshotv1.VolumeSnapshotCondition{{
+				Type:               snapshotv1.VolumeSnapshotConditionError,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: failed,
+				Message:            "volume not found",
+			}}},
+			expected: &csisnapshotv1.VolumeSnapshotStatus{
+				ReadyToUse: boolPtr(false),
+				Error:      &csisnapshotv1.VolumeSnapshotError{Time: &failed, Message: stringPtr("volume not found")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := CSISnapshotStatus(tt.status)
+			if diff := cmp.Diff(tt.expected, status); diff != "" {
+				t.Errorf("unexpected status (-expected +actual):\n%s", diff)
+			}
+			if ready := IsLegacySnapshotReady(tt.status); ready != *tt.expected.ReadyToUse {
+				t.Errorf("expected legacy snapshot ready %v, got %v", *tt.expected.ReadyToUse, ready)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// Package hostguard keeps the plugin from mutating the host cluster while it is paused
+// or running in dry-run mode. Reads are passed through, so syncers keep computing what
+// they would do. The mode can be switched at runtime.
+package hostguard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/loft-sh/vcluster-sdk/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	redacted        = "<redacted>"
+	redactedChanged = "<redacted, changed>"
+)
+
+// NewManager returns a manager whose client guards writes according to the current mode
+// of the switch.
+func NewManager(manager ctrl.Manager, modes *Switch) ctrl.Manager {
+	return &guardedManager{
+		Manager: manager,
+		client:  NewClient(manager.GetClient(), modes),
+	}
+}
+
+type guardedManager struct {
+	ctrl.Manager
+
+	client client.Client
+}
+
+func (m *guardedManager) GetClient() client.Client {
+	return m.client
+}
+
+// NewClient returns a client which guards writes according to the current mode of the
+// switch.
+func NewClient(c client.Client, modes *Switch) client.Client {
+	return &guardedClient{
+		Client: c,
+		modes:  modes,
+		log:    log.New("hostguard"),
+	}
+}
+
+type guardedClient struct {
+	client.Client
+
+	modes *Switch
+	log   log.Logger
+}
+
+// skip returns true if writes are skipped in the current mode and logs the write
+// described by message in dry-run mode.
+func (c *guardedClient) skip(message func() string) bool {
+	switch c.modes.Mode() {
+	case ModeActive:
+		return false
+	case ModeDryRun:
+		c.log.Infof("dry-run: would %s", message())
+	}
+	return true
+}
+
+func (c *guardedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.skip(func() string { return fmt.Sprintf("create %s:\n%s", c.describe(obj), c.toYAML(obj)) }) {
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *guardedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if c.skip(func() string { return fmt.Sprintf("update %s:\n%s", c.describe(obj), c.diff(ctx, obj)) }) {
+		return nil
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *guardedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if c.skip(func() string { return fmt.Sprintf("patch %s with %s", c.describe(obj), patchData(obj, patch)) }) {
+		return nil
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *guardedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if c.skip(func() string { return "delete " + c.describe(obj) }) {
+		return nil
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *guardedClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	if c.skip(func() string { return "delete all of " + c.kind(obj) }) {
+		return nil
+	}
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+func (c *guardedClient) Status() client.SubResourceWriter {
+	return &guardedSubResourceClient{
+		SubResourceWriter: c.Client.Status(),
+		client:            c,
+		subResource:       "status",
+	}
+}
+
+func (c *guardedClient) SubResource(subResource string) client.SubResourceClient {
+	subResourceClient := c.Client.SubResource(subResource)
+	return &guardedSubResourceClient{
+		SubResourceReader: subResourceClient,
+		SubResourceWriter: subResourceClient,
+		client:            c,
+		subResource:       subResource,
+	}
+}
+
+type guardedSubResourceClient struct {
+	client.SubResourceReader
+	client.SubResourceWriter
+
+	client      *guardedClient
+	subResource string
+}
+
+func (s *guardedSubResourceClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	if s.client.skip(func() string {
+		return fmt.Sprintf("create %s of %s:\n%s", s.subResource, s.client.describe(obj), s.client.toYAML(subResource))
+	}) {
+		return nil
+	}
+	return s.SubResourceWriter.Create(ctx, obj, subResource, opts...)
+}
+
+func (s *guardedSubResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	if s.client.skip(func() string {
+		return fmt.Sprintf("update %s of %s:\n%s", s.subResource, s.client.describe(obj), s.client.diff(ctx, obj))
+	}) {
+		return nil
+	}
+	return s.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+func (s *guardedSubResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if s.client.skip(func() string {
+		return fmt.Sprintf("patch %s of %s with %s", s.subResource, s.client.describe(obj), patchData(obj, patch))
+	}) {
+		return nil
+	}
+	return s.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// diff returns the difference between the current object in the cluster and obj.
+func (c *guardedClient) diff(ctx context.Context, obj client.Object) string {
+	current := obj.DeepCopyObject().(client.Object)
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+		return fmt.Sprintf("<error getting current object: %v>", err)
+	}
+
+	currentMap, err := toMap(current)
+	if err != nil {
+		return fmt.Sprintf("<error converting current object: %v>", err)
+	}
+	desiredMap, err := toMap(obj)
+	if err != nil {
+		return fmt.Sprintf("<error converting object: %v>", err)
+	}
+	if c.isSecret(obj) {
+		// the current values are needed to mark changed values of the desired object
+		redactSecret(desiredMap, currentMap)
+		redactSecret(currentMap, nil)
+	}
+
+	if diff := cmp.Diff(currentMap, desiredMap); diff != "" {
+		return diff
+	}
+	return "<no changes>"
+}
+
+func (c *guardedClient) describe(obj client.Object) string {
+	if obj.GetNamespace() == "" {
+		return c.kind(obj) + " " + obj.GetName()
+	}
+	return c.kind(obj) + " " + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+func (c *guardedClient) kind(obj client.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return fmt.Sprintf("%T", obj)
+	}
+	return gvk.Kind
+}
+
+func (c *guardedClient) isSecret(obj client.Object) bool {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return false
+	}
+	return gvk.GroupKind() == corev1.SchemeGroupVersion.WithKind("Secret").GroupKind()
+}
+
+// redactSecret replaces the values of a Secret, like the Portworx token of the plugin,
+// so they don't end up in the logs. Values which differ from the ones of current are
+// marked as changed.
+func redactSecret(secret, current map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := secret[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		currentValues, _ := current[field].(map[string]interface{})
+		for key, value := range values {
+			if currentValue, ok := currentValues[key]; ok && currentValue != value {
+				values[key] = redactedChanged
+			} else {
+				values[key] = redacted
+			}
+		}
+	}
+}
+
+// toMap converts obj without the fields which change on every write.
+func toMap(obj client.Object) (map[string]interface{}, error) {
+	obj = obj.DeepCopyObject().(client.Object)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+	obj.SetGeneration(0)
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+// toYAML returns obj as YAML, with the values of Secrets redacted.
+func (c *guardedClient) toYAML(obj client.Object) string {
+	objMap, err := toMap(obj)
+	if err != nil {
+		return fmt.Sprintf("<error converting object: %v>", err)
+	}
+	if c.isSecret(obj) {
+		redactSecret(objMap, nil)
+	}
+
+	data, err := yaml.Marshal(objMap)
+	if err != nil {
+		return fmt.Sprintf("<error marshaling object: %v>", err)
+	}
+	return string(data)
+}
+
+func patchData(obj client.Object, patch client.Patch) string {
+	data, err := patch.Data(obj)
+	if err != nil {
+		return fmt.Sprintf("<error computing patch: %v>", err)
+	}
+	return fmt.Sprintf("%s patch %s", patch.Type(), data)
+}
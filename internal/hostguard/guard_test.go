@@ -0,0 +1,178 @@
+package hostguard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testSecret(token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vcluster", Name: "px-user-token"},
+		Data:       map[string][]byte{"auth-token": []byte(token)},
+		StringData: map[string]string{"issuer": token},
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	unstructuredSecret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"namespace": "vcluster", "name": "px-user-token"},
+		"stringData": map[string]interface{}{"auth-token": "secret-token"},
+	}}
+	c := &guardedClient{
+		Client: fake.NewClientBuilder().WithObjects(testSecret("secret-token")).Build(),
+		modes:  NewSwitch(ModeDryRun),
+	}
+
+	tests := []struct {
+		name     string
+		output   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "yaml",
+			output:   c.toYAML(testSecret("secret-token")),
+			contains: []string{"auth-token: <redacted>", "issuer: <redacted>"},
+		},
+		{
+			name:     "unstructured yaml",
+			output:   c.toYAML(unstructuredSecret),
+			contains: []string{"auth-token: <redacted>"},
+		},
+		{
+			name:     "diff",
+			output:   c.diff(context.Background(), testSecret("other-token")),
+			contains: []string{"<redacted, changed>"},
+		},
+		{
+			name:     "diff of unchanged values",
+			output:   c.diff(context.Background(), testSecret("secret-token")),
+			excludes: []string{"<redacted, changed>"},
+		},
+		{
+			name: "config map",
+			output: c.toYAML(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "vcluster", Name: "config"},
+				Data:       map[string]string{"key": "value"},
+			}),
+			contains: []string{"key: value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excludes := append([]string{"secret-token", "other-token", "c2VjcmV0LXRva2Vu"}, tt.excludes...)
+			for _, value := range excludes {
+				if strings.Contains(tt.output, value) {
+					t.Errorf("expected no %q in:\n%s", value, tt.output)
+				}
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(tt.output, expected) {
+					t.Errorf("expected %q in:\n%s", expected, tt.output)
+				}
+			}
+		})
+	}
+}
+
+func TestSwitchModes(t *testing.T) {
+	ctx := context.Background()
+	fakeClient := fake.NewClientBuilder().Build()
+	modes := NewSwitch(ModePaused)
+	activated := modes.Activated()
+	c := NewClient(fakeClient, modes)
+
+	tests := []struct {
+		name      string
+		update    func()
+		created   bool
+		activated bool
+	}{
+		{
+			name:   "default mode",
+			update: func() {},
+		},
+		{
+			name:      "switched to active",
+			update:    func() { modes.Set(ModeActive) },
+			created:   true,
+			activated: true,
+		},
+		{
+			name:    "set to active again",
+			update:  func() { modes.Set(ModeActive) },
+			created: true,
+		},
+		{
+			name:   "switched to dry-run",
+			update: func() { modes.Set(ModeDryRun) },
+		},
+		{
+			name:   "reset to default mode",
+			update: func() { modes.Reset() },
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.update()
+
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "vcluster", Name: fmt.Sprintf("config-%d", i)}}
+			if err := c.Create(ctx, configMap); err != nil {
+				t.Fatalf("create config map: %v", err)
+			}
+			err := fakeClient.Get(ctx, client.ObjectKeyFromObject(configMap), &corev1.ConfigMap{})
+			if created := err == nil; created != tt.created {
+				t.Errorf("expected created %v, got %v", tt.created, err)
+			}
+
+			select {
+			case <-activated:
+				if !tt.activated {
+					t.Errorf("unexpected activation")
+				}
+			default:
+				if tt.activated {
+					t.Errorf("expected activation")
+				}
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Mode
+		err      bool
+	}{
+		{value: "", expected: ModeActive},
+		{value: "active", expected: ModeActive},
+		{value: "paused", expected: ModePaused},
+		{value: "dry-run", expected: ModeDryRun},
+		{value: "off", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			mode, err := ParseMode(tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if mode != tt.expected {
+				t.Errorf("expected mode %q, got %q", tt.expected, mode)
+			}
+		})
+	}
+}
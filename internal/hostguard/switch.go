@@ -0,0 +1,116 @@
+package hostguard
+
+import (
+	"sync"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/pkg/errors"
+)
+
+// Mode defines which writes to the host cluster are performed.
+type Mode string
+
+const (
+	// ModeActive performs all writes.
+	ModeActive Mode = ""
+	// ModePaused skips all writes.
+	ModePaused Mode = "paused"
+	// ModeDryRun skips all writes and logs them as diff against the current object.
+	ModeDryRun Mode = "dry-run"
+)
+
+// ParseMode parses the name of a mode, "active" or an empty value for ModeActive.
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(value); mode {
+	case ModeActive, ModePaused, ModeDryRun:
+		return mode, nil
+	case "active":
+		return ModeActive, nil
+	}
+
+	return "", errors.Errorf("unknown mode %q, expected active, %s or %s", value, ModePaused, ModeDryRun)
+}
+
+func (m Mode) String() string {
+	if m == ModeActive {
+		return "active"
+	}
+	return string(m)
+}
+
+// Switch holds the mode of the guarded clients. It starts with a default mode, which
+// can be overridden at runtime, e.g. to pause the plugin without restarting it.
+type Switch struct {
+	defaultMode Mode
+	log         log.Logger
+
+	lock      sync.RWMutex
+	mode      Mode
+	activated []chan struct{}
+}
+
+// NewSwitch returns a switch in the default mode.
+func NewSwitch(defaultMode Mode) *Switch {
+	s := &Switch{
+		defaultMode: defaultMode,
+		log:         log.New("hostguard"),
+		mode:        defaultMode,
+	}
+	s.logMode()
+	return s
+}
+
+// Mode returns the current mode.
+func (s *Switch) Mode() Mode {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.mode
+}
+
+// Set overrides the default mode.
+func (s *Switch) Set(mode Mode) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.mode == mode {
+		return
+	}
+	s.mode = mode
+	s.logMode()
+
+	if mode == ModeActive {
+		for _, activated := range s.activated {
+			// a pending notification already covers this change
+			select {
+			case activated <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Activated returns a channel which receives a value whenever the switch changes to
+// ModeActive, so writes skipped before can be performed again. Changes happening while
+// a notification is pending are merged into it.
+func (s *Switch) Activated() <-chan struct{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	activated := make(chan struct{}, 1)
+	s.activated = append(s.activated, activated)
+	return activated
+}
+
+// Reset switches back to the default mode.
+func (s *Switch) Reset() {
+	s.Set(s.defaultMode)
+}
+
+func (s *Switch) logMode() {
+	if s.mode == ModeActive {
+		s.log.Infof("Plugin runs in active mode, the host cluster is modified")
+		return
+	}
+	s.log.Infof("Plugin runs in %s mode, the host cluster is not modified", s.mode)
+}
@@ -0,0 +1,120 @@
+// Package metrics defines the Prometheus metrics of the plugin and serves them together
+// with the controller-runtime metrics.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "pxe"
+
+// Labels of the metrics of virtual PVCs.
+var volumeLabels = []string{"vcluster", "namespace", "persistentvolumeclaim"}
+
+var (
+	// VolumeUsedBytes is the number of bytes used by the Portworx volume of a virtual PVC.
+	VolumeUsedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "volume_used_bytes",
+		Help:      "Number of bytes used by the Portworx volume of a virtual PVC.",
+	}, volumeLabels)
+	// VolumeCapacityBytes is the provisioned size of the Portworx volume of a virtual PVC.
+	VolumeCapacityBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "volume_capacity_bytes",
+		Help:      "Provisioned size of the Portworx volume of a virtual PVC.",
+	}, volumeLabels)
+	// VolumeReplicas is the current number of replicas of the Portworx volume.
+	VolumeReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "volume_replicas",
+		Help:      "Current number of replicas of the Portworx volume of a virtual PVC.",
+	}, volumeLabels)
+	// VolumeHALevel is the requested number of replicas of the Portworx volume.
+	VolumeHALevel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "volume_ha_level",
+		Help:      "Requested number of replicas of the Portworx volume of a virtual PVC.",
+	}, volumeLabels)
+	// VolumeHealthy is 1 if the Portworx volume is up with all replicas, 0 otherwise.
+	VolumeHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "volume_healthy",
+		Help:      "1 if the Portworx volume of a virtual PVC is up with all replicas, 0 otherwise.",
+	}, volumeLabels)
+)
+
+// Labels of the chargeback metrics.
+var chargebackLabels = []string{"vcluster", "namespace", "resource", "storageclass"}
+
+var (
+	// ChargebackCount is the number of billed objects per namespace, resource and storage class.
+	ChargebackCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chargeback_objects",
+		Help:      "Number of billed PVCs or snapshots per virtual namespace.",
+	}, chargebackLabels)
+	// ChargebackProvisionedBytes is the provisioned capacity per namespace, resource and storage class.
+	ChargebackProvisionedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chargeback_provisioned_bytes",
+		Help:      "Provisioned capacity of PVCs per virtual namespace.",
+	}, chargebackLabels)
+	// ChargebackUsedBytes is the used capacity per namespace, resource and storage class.
+	ChargebackUsedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chargeback_used_bytes",
+		Help:      "Used capacity of PVCs, snapshots and cloud snapshots per virtual namespace.",
+	}, chargebackLabels)
+)
+
+// VolumeCollectors are all metrics labelled by virtual PVC.
+var VolumeCollectors = []*prometheus.GaugeVec{
+	VolumeUsedBytes,
+	VolumeCapacityBytes,
+	VolumeReplicas,
+	VolumeHALevel,
+	VolumeHealthy,
+}
+
+func init() {
+	for _, c := range VolumeCollectors {
+		ctrlmetrics.Registry.MustRegister(c)
+	}
+	ctrlmetrics.Registry.MustRegister(ChargebackCount, ChargebackProvisionedBytes, ChargebackUsedBytes)
+}
+
+// Start serves the metrics on addr until ctx is done. An address of "0" disables the
+// metrics endpoint.
+func Start(ctx context.Context, addr string) {
+	if addr == "" || addr == "0" {
+		return
+	}
+
+	logger := log.New("metrics")
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(ctrlmetrics.Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		logger.Infof("Serving metrics on %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("error serving metrics: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,233 @@
+// Package openstorage is a client for the OpenStorage SDK, the gRPC API Portworx serves
+// through the portworx-api Service.
+package openstorage
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultEndpoint is the gRPC endpoint of the portworx-api Service on the host cluster.
+const DefaultEndpoint = "portworx-api.kube-system.svc:9020"
+
+const (
+	methodVolumeInspect     = "/openstorage.api.OpenStorageVolume/Inspect"
+	methodVolumeDelete      = "/openstorage.api.OpenStorageVolume/Delete"
+	methodSnapshotEnumerate = "/openstorage.api.OpenStorageVolume/SnapshotEnumerateWithFilters"
+	methodIdentityVersion   = "/openstorage.api.OpenStorageIdentity/Version"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// DefaultBackoff is used to retry calls failing with a transient error.
+var DefaultBackoff = wait.Backoff{
+	Steps:    4,
+	Duration: 200 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+}
+
+// TokenSource returns the token used to authenticate against clusters with PX-Security.
+// An empty token sends unauthenticated requests.
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken returns a token source always returning token.
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// SecretTokenSource returns a token source reading the token from the given key of a
+// Secret on every call, so rotated tokens are picked up.
+func SecretTokenSource(reader client.Reader, key client.ObjectKey, dataKey string) TokenSource {
+	return func(ctx context.Context) (string, error) {
+		secret := &corev1.Secret{}
+		if err := reader.Get(ctx, key, secret); err != nil {
+			return "", errors.Wrap(err, "get token secret")
+		}
+		return string(secret.Data[dataKey]), nil
+	}
+}
+
+// Options configure a client.
+type Options struct {
+	// Endpoint is the address of the SDK server, defaults to DefaultEndpoint
+	Endpoint string
+	// Token authenticates requests, if set
+	Token TokenSource
+	// TLS enables transport security, the SDK is served in plain text by default
+	TLS *tls.Config
+	// Timeout is the timeout of a single attempt, defaults to 10 seconds
+	Timeout time.Duration
+	// Backoff is used to retry transient errors, defaults to DefaultBackoff
+	Backoff *wait.Backoff
+	// DialOptions are appended to the options used to dial the server
+	DialOptions []grpc.DialOption
+}
+
+// Client is a client for the OpenStorage SDK. The connection is established lazily and
+// re-established by gRPC whenever it breaks.
+type Client struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+	backoff wait.Backoff
+}
+
+// NewClient returns a client for the SDK server described by opts.
+func NewClient(opts Options) (*Client, error) {
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultEndpoint
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = &DefaultBackoff
+	}
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})),
+	}
+	if opts.TLS != nil {
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(credentials.NewTLS(opts.TLS)))
+	} else {
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if opts.Token != nil {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(&tokenCredentials{
+			source: opts.Token,
+			secure: opts.TLS != nil,
+		}))
+	}
+	dialOptions = append(dialOptions, opts.DialOptions...)
+
+	conn, err := grpc.Dial(opts.Endpoint, dialOptions...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial %s", opts.Endpoint)
+	}
+
+	return &Client{
+		conn:    conn,
+		timeout: opts.Timeout,
+		backoff: *opts.Backoff,
+	}, nil
+}
+
+// Close closes the connection of the client.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// InspectVolume returns the volume or snapshot with the given id.
+func (c *Client) InspectVolume(ctx context.Context, volumeID string) (*Volume, error) {
+	resp := &volumeInspectResponse{}
+	if err := c.invoke(ctx, methodVolumeInspect, &volumeInspectRequest{VolumeID: volumeID}, resp); err != nil {
+		return nil, errors.Wrapf(err, "inspect volume %s", volumeID)
+	}
+	if resp.Volume == nil {
+		return nil, errors.Errorf("inspect volume %s: empty response", volumeID)
+	}
+
+	return resp.Volume, nil
+}
+
+// DeleteVolume deletes the given volume or snapshot.
+func (c *Client) DeleteVolume(ctx context.Context, volumeID string) error {
+	if err := c.invoke(ctx, methodVolumeDelete, &volumeDeleteRequest{VolumeID: volumeID}, &volumeDeleteResponse{}); err != nil {
+		return errors.Wrapf(err, "delete volume %s", volumeID)
+	}
+
+	return nil
+}
+
+// EnumerateSnapshots returns the ids of all snapshots of the given volume.
+func (c *Client) EnumerateSnapshots(ctx context.Context, volumeID string) ([]string, error) {
+	resp := &snapshotEnumerateResponse{}
+	if err := c.invoke(ctx, methodSnapshotEnumerate, &snapshotEnumerateRequest{VolumeID: volumeID}, resp); err != nil {
+		return nil, errors.Wrapf(err, "enumerate snapshots of volume %s", volumeID)
+	}
+
+	return resp.SnapshotIDs, nil
+}
+
+// Version returns the SDK version of the cluster, which can be used as health check.
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	resp := &versionResponse{}
+	if err := c.invoke(ctx, methodIdentityVersion, &versionRequest{}, resp); err != nil {
+		return nil, errors.Wrap(err, "get version")
+	}
+	if resp.SDKVersion == nil {
+		return nil, errors.New("get version: empty response")
+	}
+
+	return resp.SDKVersion, nil
+}
+
+// invoke calls the method and retries transient errors with the backoff of the client.
+func (c *Client) invoke(ctx context.Context, method string, req, resp wireMessage) error {
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(ctx, c.backoff, func() (bool, error) {
+		callCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+
+		lastErr = c.conn.Invoke(callCtx, method, req, resp)
+		if lastErr == nil {
+			return true, nil
+		}
+		if isTransient(lastErr) {
+			return false, nil
+		}
+		return false, lastErr
+	})
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+
+	return err
+}
+
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// IsNotFound returns true if the error reports a missing volume or snapshot.
+func IsNotFound(err error) bool {
+	return status.Code(errors.Cause(err)) == codes.NotFound
+}
+
+type tokenCredentials struct {
+	source TokenSource
+	secure bool
+}
+
+func (c *tokenCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := c.source(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, nil
+	}
+
+	return map[string]string{"authorization": "bearer " + token}, nil
+}
+
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
@@ -0,0 +1,171 @@
+package openstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func newTestClient(t *testing.T, server *FakeServer, token TokenSource) *Client {
+	t.Helper()
+
+	c, err := NewClient(Options{
+		Endpoint:    "bufnet",
+		Token:       token,
+		Timeout:     time.Second,
+		Backoff:     &wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 1},
+		DialOptions: server.DialOptions(),
+	})
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func newTestServer(t *testing.T) *FakeServer {
+	t.Helper()
+
+	server := NewFakeServer()
+	t.Cleanup(server.Stop)
+	return server
+}
+
+func TestClientInspectVolume(t *testing.T) {
+	server := newTestServer(t)
+	volume := Volume{
+		ID:          "1234",
+		Locator:     VolumeLocator{Name: "pvc-1"},
+		Spec:        VolumeSpec{Size: 10 << 30, HALevel: 2},
+		Usage:       1 << 30,
+		Status:      VolumeStatusUp,
+		ReplicaSets: []ReplicaSet{{Nodes: []string{"node-1", "node-2"}}},
+	}
+	server.SetVolume(volume)
+	c := newTestClient(t, server, nil)
+
+	got, err := c.InspectVolume(context.Background(), "1234")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&volume, got); diff != "" {
+		t.Errorf("unexpected volume (-want +got):\n%s", diff)
+	}
+
+	if _, err := c.InspectVolume(context.Background(), "missing"); !IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestClientSnapshots(t *testing.T) {
+	server := newTestServer(t)
+	server.SetVolume(Volume{ID: "1234"})
+	server.SetVolume(Volume{ID: "snap-1"})
+	server.SetVolume(Volume{ID: "snap-2"})
+	server.SetSnapshots("1234", "snap-1", "snap-2")
+	c := newTestClient(t, server, nil)
+
+	snapshots, err := c.EnumerateSnapshots(context.Background(), "1234")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"snap-1", "snap-2"}, snapshots); diff != "" {
+		t.Errorf("unexpected snapshots (-want +got):\n%s", diff)
+	}
+
+	if err := c.DeleteVolume(context.Background(), "snap-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.DeleteVolume(context.Background(), "snap-1"); !IsNotFound(err) {
+		t.Errorf("expected not found error deleting twice, got %v", err)
+	}
+
+	snapshots, err = c.EnumerateSnapshots(context.Background(), "1234")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"snap-2"}, snapshots); diff != "" {
+		t.Errorf("unexpected snapshots after delete (-want +got):\n%s", diff)
+	}
+}
+
+func TestClientVersion(t *testing.T) {
+	c := newTestClient(t, newTestServer(t), nil)
+
+	version, err := c.Version(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version.Minor != 101 || version.Version != "0.101.0-fake" {
+		t.Errorf("unexpected version %+v", version)
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	server := newTestServer(t)
+	server.RequireToken("secret")
+
+	tests := []struct {
+		name  string
+		token TokenSource
+		code  codes.Code
+	}{
+		{name: "no token", code: codes.Unauthenticated},
+		{name: "empty token", token: StaticToken(""), code: codes.Unauthenticated},
+		{name: "wrong token", token: StaticToken("wrong"), code: codes.Unauthenticated},
+		{name: "valid token", token: StaticToken("secret"), code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, server, tt.token)
+			_, err := c.Version(context.Background())
+			if code := status.Code(errors.Cause(err)); code != tt.code {
+				t.Errorf("expected code %s, got %s (%v)", tt.code, code, err)
+			}
+		})
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []codes.Code
+		code     codes.Code
+	}{
+		{
+			name:     "transient failures are retried",
+			failures: []codes.Code{codes.Unavailable, codes.ResourceExhausted},
+			code:     codes.OK,
+		},
+		{
+			name:     "persistent transient failures are returned",
+			failures: []codes.Code{codes.Unavailable, codes.Unavailable, codes.Unavailable},
+			code:     codes.Unavailable,
+		},
+		{
+			name:     "permanent failures are not retried",
+			failures: []codes.Code{codes.PermissionDenied, codes.Unavailable},
+			code:     codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.FailNext(tt.failures...)
+			c := newTestClient(t, server, nil)
+
+			_, err := c.Version(context.Background())
+			if code := status.Code(errors.Cause(err)); code != tt.code {
+				t.Errorf("expected code %s, got %s (%v)", tt.code, code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package openstorage
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// FakeServer is an in-process SDK server for tests. Clients connect through the dial
+// options returned by DialOptions.
+type FakeServer struct {
+	mu        sync.Mutex
+	volumes   map[string]Volume
+	snapshots map[string][]string
+	token     string
+	failures  []codes.Code
+
+	listener *bufconn.Listener
+	server   *grpc.Server
+}
+
+// NewFakeServer starts a fake SDK server.
+func NewFakeServer() *FakeServer {
+	s := &FakeServer{
+		volumes:   map[string]Volume{},
+		snapshots: map[string][]string{},
+		listener:  bufconn.Listen(1 << 20),
+	}
+	s.server = grpc.NewServer(
+		grpc.ForceServerCodec(codec{}),
+		grpc.UnaryInterceptor(s.intercept),
+	)
+	s.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "openstorage.api.OpenStorageVolume",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "Inspect", Handler: fakeHandler(func() wireMessage { return &volumeInspectRequest{} }, s.inspect)},
+			{MethodName: "Delete", Handler: fakeHandler(func() wireMessage { return &volumeDeleteRequest{} }, s.delete)},
+			{MethodName: "SnapshotEnumerateWithFilters", Handler: fakeHandler(func() wireMessage { return &snapshotEnumerateRequest{} }, s.enumerateSnapshots)},
+		},
+	}, s)
+	s.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "openstorage.api.OpenStorageIdentity",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "Version", Handler: fakeHandler(func() wireMessage { return &versionRequest{} }, s.version)},
+		},
+	}, s)
+
+	go func() {
+		_ = s.server.Serve(s.listener)
+	}()
+	return s
+}
+
+// DialOptions returns the options to connect a client to the server. The endpoint of
+// the client is ignored.
+func (s *FakeServer) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+	}
+}
+
+// Stop stops the server.
+func (s *FakeServer) Stop() {
+	s.server.Stop()
+}
+
+// SetVolume adds or replaces a volume.
+func (s *FakeServer) SetVolume(volume Volume) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.volumes[volume.ID] = volume
+}
+
+// SetSnapshots sets the snapshots of a volume.
+func (s *FakeServer) SetSnapshots(volumeID string, snapshotIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[volumeID] = snapshotIDs
+}
+
+// RequireToken rejects requests without the given bearer token.
+func (s *FakeServer) RequireToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = token
+}
+
+// FailNext fails the next calls with the given codes, one per call.
+func (s *FakeServer) FailNext(codes ...codes.Code) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, codes...)
+}
+
+func (s *FakeServer) intercept(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	s.mu.Lock()
+	token := s.token
+	var failure codes.Code
+	if len(s.failures) > 0 {
+		failure, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if failure != codes.OK {
+		return nil, status.Error(failure, "injected failure")
+	}
+	if token != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("authorization"); len(values) == 0 || values[0] != "bearer "+token {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+func (s *FakeServer) inspect(in wireMessage) (wireMessage, error) {
+	req := in.(*volumeInspectRequest)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	volume, ok := s.volumes[req.VolumeID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "volume %s not found", req.VolumeID)
+	}
+	return &volumeInspectResponse{Volume: &volume, Name: volume.Locator.Name}, nil
+}
+
+func (s *FakeServer) delete(in wireMessage) (wireMessage, error) {
+	req := in.(*volumeDeleteRequest)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.volumes[req.VolumeID]; !ok {
+		return nil, status.Errorf(codes.NotFound, "volume %s not found", req.VolumeID)
+	}
+	delete(s.volumes, req.VolumeID)
+	for volumeID, snapshotIDs := range s.snapshots {
+		remaining := []string{}
+		for _, snapshotID := range snapshotIDs {
+			if snapshotID != req.VolumeID {
+				remaining = append(remaining, snapshotID)
+			}
+		}
+		s.snapshots[volumeID] = remaining
+	}
+	return &volumeDeleteResponse{}, nil
+}
+
+func (s *FakeServer) enumerateSnapshots(in wireMessage) (wireMessage, error) {
+	req := in.(*snapshotEnumerateRequest)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &snapshotEnumerateResponse{SnapshotIDs: s.snapshots[req.VolumeID]}, nil
+}
+
+func (s *FakeServer) version(wireMessage) (wireMessage, error) {
+	return &versionResponse{SDKVersion: &Version{Major: 0, Minor: 101, Version: "0.101.0-fake"}}, nil
+}
+
+// fakeHandler adapts a handler to a gRPC method handler.
+func fakeHandler(
+	newRequest func() wireMessage,
+	fn func(wireMessage) (wireMessage, error),
+) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := newRequest()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{}, func(_ context.Context, req interface{}) (interface{}, error) {
+			return fn(req.(wireMessage))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package openstorage
+
+// The types below mirror the subset of the OpenStorage SDK messages used by the plugin.
+// Field numbers match api.proto of libopenstorage/openstorage, fields not listed are
+// skipped when decoding.
+
+// VolumeStatus is the availability status of a volume.
+type VolumeStatus int32
+
+const (
+	VolumeStatusNone       VolumeStatus = 0
+	VolumeStatusNotPresent VolumeStatus = 1
+	VolumeStatusUp         VolumeStatus = 2
+	VolumeStatusDown       VolumeStatus = 3
+	VolumeStatusDegraded   VolumeStatus = 4
+)
+
+func (s VolumeStatus) String() string {
+	switch s {
+	case VolumeStatusNone:
+		return "none"
+	case VolumeStatusNotPresent:
+		return "not-present"
+	case VolumeStatusUp:
+		return "up"
+	case VolumeStatusDown:
+		return "down"
+	case VolumeStatusDegraded:
+		return "degraded"
+	}
+	return "unknown"
+}
+
+// IoProfile is the IO profile of a volume.
+type IoProfile int32
+
+const (
+	IoProfileSequential  IoProfile = 0
+	IoProfileRandom      IoProfile = 1
+	IoProfileDB          IoProfile = 2
+	IoProfileDBRemote    IoProfile = 3
+	IoProfileCMS         IoProfile = 4
+	IoProfileSyncShared  IoProfile = 5
+	IoProfileAuto        IoProfile = 6
+	IoProfileNone        IoProfile = 7
+	IoProfileJournal     IoProfile = 8
+	IoProfileAutoJournal IoProfile = 9
+)
+
+func (p IoProfile) String() string {
+	switch p {
+	case IoProfileSequential:
+		return "sequential"
+	case IoProfileRandom:
+		return "random"
+	case IoProfileDB:
+		return "db"
+	case IoProfileDBRemote:
+		return "db_remote"
+	case IoProfileCMS:
+		return "cms"
+	case IoProfileSyncShared:
+		return "sync_shared"
+	case IoProfileAuto:
+		return "auto"
+	case IoProfileNone:
+		return "none"
+	case IoProfileJournal:
+		return "journal"
+	case IoProfileAutoJournal:
+		return "auto_journal"
+	}
+	return "unknown"
+}
+
+// Volume is a Portworx volume or snapshot.
+type Volume struct {
+	// ID is the id of the volume
+	ID string
+	// Locator holds the name of the volume
+	Locator VolumeLocator
+	// Spec is the requested configuration of the volume
+	Spec VolumeSpec
+	// Usage is the number of bytes used by the volume
+	Usage uint64
+	// Status is the availability of the volume
+	Status VolumeStatus
+	// AttachedOn is the node the volume is attached on
+	AttachedOn string
+	// ReplicaSets are the nodes holding replicas of the volume
+	ReplicaSets []ReplicaSet
+	// Error is the last error of the volume
+	Error string
+}
+
+// VolumeLocator identifies a volume.
+type VolumeLocator struct {
+	Name string
+	// VolumeLabels are the labels of the volume, e.g. the labels Stork copies from the
+	// VolumeSnapshot to the snapshots it takes
+	VolumeLabels map[string]string
+}
+
+// VolumeSpec is the configuration of a volume.
+type VolumeSpec struct {
+	// Size is the provisioned size in bytes
+	Size uint64
+	// HALevel is the number of replicas
+	HALevel int64
+	// IoProfile is the IO profile of the volume
+	IoProfile IoProfile
+	// Encrypted is true for encrypted volumes
+	Encrypted bool
+}
+
+// ReplicaSet is a set of nodes holding a replica of a volume.
+type ReplicaSet struct {
+	Nodes []string
+}
+
+// Version is the version of the SDK served by the cluster.
+type Version struct {
+	Major   int32
+	Minor   int32
+	Patch   int32
+	Version string
+}
+
+type volumeInspectRequest struct {
+	VolumeID string
+}
+
+type volumeInspectResponse struct {
+	Volume *Volume
+	Name   string
+}
+
+type volumeDeleteRequest struct {
+	VolumeID string
+}
+
+type volumeDeleteResponse struct{}
+
+type snapshotEnumerateRequest struct {
+	VolumeID string
+}
+
+type snapshotEnumerateResponse struct {
+	SnapshotIDs []string
+}
+
+type versionRequest struct{}
+
+type versionResponse struct {
+	SDKVersion *Version
+}
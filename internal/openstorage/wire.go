@@ -0,0 +1,386 @@
+package openstorage
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// codec encodes the SDK messages in protobuf wire format, which avoids depending on the
+// generated OpenStorage API package.
+type codec struct{}
+
+type wireMessage interface {
+	marshal() []byte
+	unmarshal(b []byte) error
+}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(wireMessage)
+	if !ok {
+		return nil, errors.Errorf("cannot marshal %T", v)
+	}
+	return m.marshal(), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(wireMessage)
+	if !ok {
+		return errors.Errorf("cannot unmarshal %T", v)
+	}
+	return m.unmarshal(data)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+// decodeFields calls fn for each field of a message, fn returns the number of bytes
+// consumed. Fields fn doesn't know are skipped by skipField.
+func decodeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := fn(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func skipField(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	return protowire.ConsumeFieldValue(num, typ, b), nil
+}
+
+func consumeString(b []byte, s *string) (int, error) {
+	v, n := protowire.ConsumeString(b)
+	*s = v
+	return n, nil
+}
+
+func consumeVarint(b []byte, fn func(uint64)) (int, error) {
+	v, n := protowire.ConsumeVarint(b)
+	if n >= 0 {
+		fn(v)
+	}
+	return n, nil
+}
+
+func consumeMessage(b []byte, m wireMessage) (int, error) {
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n, nil
+	}
+	return n, m.unmarshal(v)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendMessage(b []byte, num protowire.Number, m wireMessage) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.marshal())
+}
+
+func (v *Volume) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, v.ID)
+	b = appendMessage(b, 5, &v.Locator)
+	b = appendMessage(b, 7, &v.Spec)
+	b = appendVarint(b, 8, v.Usage)
+	b = appendVarint(b, 11, uint64(v.Status))
+	b = appendString(b, 13, v.AttachedOn)
+	for i := range v.ReplicaSets {
+		b = appendMessage(b, 19, &v.ReplicaSets[i])
+	}
+	b = appendString(b, 21, v.Error)
+	return b
+}
+
+func (v *Volume) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeString(b, &v.ID)
+		case num == 5 && typ == protowire.BytesType:
+			return consumeMessage(b, &v.Locator)
+		case num == 7 && typ == protowire.BytesType:
+			return consumeMessage(b, &v.Spec)
+		case num == 8 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { v.Usage = x })
+		case num == 11 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { v.Status = VolumeStatus(x) })
+		case num == 13 && typ == protowire.BytesType:
+			return consumeString(b, &v.AttachedOn)
+		case num == 19 && typ == protowire.BytesType:
+			v.ReplicaSets = append(v.ReplicaSets, ReplicaSet{})
+			return consumeMessage(b, &v.ReplicaSets[len(v.ReplicaSets)-1])
+		case num == 21 && typ == protowire.BytesType:
+			return consumeString(b, &v.Error)
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (l *VolumeLocator) marshal() []byte {
+	b := appendString(nil, 1, l.Name)
+	keys := make([]string, 0, len(l.VolumeLabels))
+	for key := range l.VolumeLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b = appendMessage(b, 2, &mapEntry{Key: key, Value: l.VolumeLabels[key]})
+	}
+	return b
+}
+
+func (l *VolumeLocator) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeString(b, &l.Name)
+		case num == 2 && typ == protowire.BytesType:
+			entry := &mapEntry{}
+			n, err := consumeMessage(b, entry)
+			if n >= 0 && err == nil {
+				if l.VolumeLabels == nil {
+					l.VolumeLabels = map[string]string{}
+				}
+				l.VolumeLabels[entry.Key] = entry.Value
+			}
+			return n, err
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+// mapEntry is an entry of a map<string, string> field.
+type mapEntry struct {
+	Key   string
+	Value string
+}
+
+func (e *mapEntry) marshal() []byte {
+	b := appendString(nil, 1, e.Key)
+	return appendString(b, 2, e.Value)
+}
+
+func (e *mapEntry) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeString(b, &e.Key)
+		case num == 2 && typ == protowire.BytesType:
+			return consumeString(b, &e.Value)
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (s *VolumeSpec) marshal() []byte {
+	var b []byte
+	b = appendVarint(b, 2, s.Size)
+	b = appendVarint(b, 5, uint64(s.HALevel))
+	b = appendVarint(b, 7, uint64(s.IoProfile))
+	b = appendVarint(b, 14, protowire.EncodeBool(s.Encrypted))
+	return b
+}
+
+func (s *VolumeSpec) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 2 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { s.Size = x })
+		case num == 5 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { s.HALevel = int64(x) })
+		case num == 7 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { s.IoProfile = IoProfile(x) })
+		case num == 14 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { s.Encrypted = protowire.DecodeBool(x) })
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (r *ReplicaSet) marshal() []byte {
+	var b []byte
+	for _, node := range r.Nodes {
+		b = appendString(b, 1, node)
+	}
+	return b
+}
+
+func (r *ReplicaSet) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			var node string
+			n, err := consumeString(b, &node)
+			r.Nodes = append(r.Nodes, node)
+			return n, err
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (v *Version) marshal() []byte {
+	var b []byte
+	b = appendVarint(b, 1, uint64(v.Major))
+	b = appendVarint(b, 2, uint64(v.Minor))
+	b = appendVarint(b, 3, uint64(v.Patch))
+	b = appendString(b, 4, v.Version)
+	return b
+}
+
+func (v *Version) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { v.Major = int32(x) })
+		case num == 2 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { v.Minor = int32(x) })
+		case num == 3 && typ == protowire.VarintType:
+			return consumeVarint(b, func(x uint64) { v.Patch = int32(x) })
+		case num == 4 && typ == protowire.BytesType:
+			return consumeString(b, &v.Version)
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (r *volumeInspectRequest) marshal() []byte {
+	return appendString(nil, 1, r.VolumeID)
+}
+
+func (r *volumeInspectRequest) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			return consumeString(b, &r.VolumeID)
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (r *volumeInspectResponse) marshal() []byte {
+	var b []byte
+	if r.Volume != nil {
+		b = appendMessage(b, 1, r.Volume)
+	}
+	b = appendString(b, 2, r.Name)
+	return b
+}
+
+func (r *volumeInspectResponse) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			r.Volume = &Volume{}
+			return consumeMessage(b, r.Volume)
+		case num == 2 && typ == protowire.BytesType:
+			return consumeString(b, &r.Name)
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (r *snapshotEnumerateRequest) marshal() []byte {
+	return appendString(nil, 1, r.VolumeID)
+}
+
+func (r *snapshotEnumerateRequest) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			return consumeString(b, &r.VolumeID)
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (r *snapshotEnumerateResponse) marshal() []byte {
+	var b []byte
+	for _, id := range r.SnapshotIDs {
+		b = appendString(b, 1, id)
+	}
+	return b
+}
+
+func (r *snapshotEnumerateResponse) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			var id string
+			n, err := consumeString(b, &id)
+			r.SnapshotIDs = append(r.SnapshotIDs, id)
+			return n, err
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (r *volumeDeleteRequest) marshal() []byte {
+	return appendString(nil, 1, r.VolumeID)
+}
+
+func (r *volumeDeleteRequest) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			return consumeString(b, &r.VolumeID)
+		}
+		return skipField(num, typ, b)
+	})
+}
+
+func (r *volumeDeleteResponse) marshal() []byte {
+	return nil
+}
+
+func (r *volumeDeleteResponse) unmarshal(b []byte) error {
+	return decodeFields(b, skipField)
+}
+
+func (r *versionRequest) marshal() []byte {
+	return nil
+}
+
+func (r *versionRequest) unmarshal(b []byte) error {
+	return decodeFields(b, skipField)
+}
+
+func (r *versionResponse) marshal() []byte {
+	if r.SDKVersion == nil {
+		return nil
+	}
+	return appendMessage(nil, 1, r.SDKVersion)
+}
+
+func (r *versionResponse) unmarshal(b []byte) error {
+	return decodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			r.SDKVersion = &Version{}
+			return consumeMessage(b, r.SDKVersion)
+		}
+		return skipField(num, typ, b)
+	})
+}
@@ -0,0 +1,139 @@
+package openstorage
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     wireMessage
+		newOut func() wireMessage
+	}{
+		{
+			name: "volume inspect response",
+			in: &volumeInspectResponse{
+				Name: "pvc-1",
+				Volume: &Volume{
+					ID: "1234",
+					Locator: VolumeLocator{
+						Name:         "pvc-1",
+						VolumeLabels: map[string]string{"pxe.portworx.io/vcluster": "tenant", "stork": ""},
+					},
+					Spec: VolumeSpec{
+						Size:      10 << 30,
+						HALevel:   3,
+						IoProfile: IoProfileDBRemote,
+						Encrypted: true,
+					},
+					Usage:      3 << 30,
+					Status:     VolumeStatusDegraded,
+					AttachedOn: "node-1",
+					ReplicaSets: []ReplicaSet{
+						{Nodes: []string{"node-1", "node-2"}},
+						{Nodes: []string{"node-3"}},
+					},
+					Error: "replica down",
+				},
+			},
+			newOut: func() wireMessage { return &volumeInspectResponse{} },
+		},
+		{
+			name:   "empty volume",
+			in:     &volumeInspectResponse{Volume: &Volume{}},
+			newOut: func() wireMessage { return &volumeInspectResponse{} },
+		},
+		{
+			name:   "volume inspect request",
+			in:     &volumeInspectRequest{VolumeID: "1234"},
+			newOut: func() wireMessage { return &volumeInspectRequest{} },
+		},
+		{
+			name:   "volume delete request",
+			in:     &volumeDeleteRequest{VolumeID: "1234"},
+			newOut: func() wireMessage { return &volumeDeleteRequest{} },
+		},
+		{
+			name:   "volume delete response",
+			in:     &volumeDeleteResponse{},
+			newOut: func() wireMessage { return &volumeDeleteResponse{} },
+		},
+		{
+			name:   "snapshot enumerate request",
+			in:     &snapshotEnumerateRequest{VolumeID: "1234"},
+			newOut: func() wireMessage { return &snapshotEnumerateRequest{} },
+		},
+		{
+			name:   "snapshot enumerate response",
+			in:     &snapshotEnumerateResponse{SnapshotIDs: []string{"5678", "9012"}},
+			newOut: func() wireMessage { return &snapshotEnumerateResponse{} },
+		},
+		{
+			name:   "version request",
+			in:     &versionRequest{},
+			newOut: func() wireMessage { return &versionRequest{} },
+		},
+		{
+			name:   "version response",
+			in:     &versionResponse{SDKVersion: &Version{Major: 0, Minor: 101, Patch: 3, Version: "0.101.3"}},
+			newOut: func() wireMessage { return &versionResponse{} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := codec{}.Marshal(tt.in)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+
+			out := tt.newOut()
+			if err := (codec{}).Unmarshal(data, out); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if diff := cmp.Diff(tt.in, out); diff != "" {
+				t.Errorf("unexpected message after round trip (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodecSkipsUnknownFields(t *testing.T) {
+	var b []byte
+	b = protowire.AppendTag(b, 99, protowire.BytesType)
+	b = protowire.AppendString(b, "unknown")
+	b = appendString(b, 1, "1234")
+	b = protowire.AppendTag(b, 100, protowire.VarintType)
+	b = protowire.AppendVarint(b, 42)
+	b = appendVarint(b, 8, 1024)
+
+	volume := &Volume{}
+	if err := volume.unmarshal(b); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if diff := cmp.Diff(&Volume{ID: "1234", Usage: 1024}, volume); diff != "" {
+		t.Errorf("unexpected volume (-want +got):\n%s", diff)
+	}
+}
+
+func TestCodecRejectsInvalidData(t *testing.T) {
+	// a string field whose length exceeds the message
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendVarint(b, 10)
+
+	if err := (&volumeInspectRequest{}).unmarshal(b); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestCodecRejectsForeignMessages(t *testing.T) {
+	if _, err := (codec{}).Marshal("not a message"); err == nil {
+		t.Errorf("expected an error marshaling a foreign message")
+	}
+	if err := (codec{}).Unmarshal(nil, new(string)); err == nil {
+		t.Errorf("expected an error unmarshaling a foreign message")
+	}
+}
@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// MetadataFilter restricts the labels and annotations propagated in one direction.
+// Patterns are either exact keys or prefixes ending with "*", e.g. "example.com/*".
+type MetadataFilter struct {
+	// Allow are the keys which are propagated. Empty allows all keys.
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny are the keys which are never propagated, even if allowed.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// MetadataRules are the filters of a single kind.
+type MetadataRules struct {
+	// Down filters the metadata synced from virtual to host objects.
+	Down MetadataFilter `json:"down,omitempty"`
+
+	// Up filters the metadata imported from host to virtual objects.
+	Up MetadataFilter `json:"up,omitempty"`
+}
+
+// MetadataPolicy restricts the labels and annotations propagated between the virtual
+// and the host cluster. Annotations which change the behaviour of Stork and Portworx are
+// validated in addition to the filters.
+type MetadataPolicy struct {
+	// Kinds are the rules per kind, e.g. VolumeSnapshot. Kinds without rules propagate
+	// all labels and annotations.
+	Kinds map[string]MetadataRules `json:"kinds,omitempty"`
+
+	// AllowCloudSnapshots allows tenants to request cloud snapshots.
+	AllowCloudSnapshots bool `json:"allowCloudSnapshots,omitempty"`
+
+	// AllowedCloudCredentials are the Portworx cloud credentials tenants may reference.
+	// Empty only allows the default credentials of the cluster.
+	AllowedCloudCredentials []string `json:"allowedCloudCredentials,omitempty"`
+}
+
+// ParseMetadataPolicy parses a policy in YAML or JSON.
+func ParseMetadataPolicy(data []byte) (*MetadataPolicy, error) {
+	policy := &MetadataPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, errors.Wrap(err, "parse metadata policy")
+	}
+
+	for kind, rules := range policy.Kinds {
+		for _, filter := range []MetadataFilter{rules.Down, rules.Up} {
+			for _, pattern := range append(append([]string{}, filter.Allow...), filter.Deny...) {
+				if strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+					return nil, errors.Errorf("invalid pattern %q of kind %s: only a trailing * is supported", pattern, kind)
+				}
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// Rules returns the rules of the given kind. A nil policy has no rules.
+func (p *MetadataPolicy) Rules(kind string) MetadataRules {
+	if p == nil {
+		return MetadataRules{}
+	}
+	return p.Kinds[kind]
+}
+
+// CloudSnapshotsAllowed returns true if tenants may request cloud snapshots.
+func (p *MetadataPolicy) CloudSnapshotsAllowed() bool {
+	return p != nil && p.AllowCloudSnapshots
+}
+
+// CloudCredentialAllowed returns true if tenants may reference the given credentials.
+func (p *MetadataPolicy) CloudCredentialAllowed(id string) bool {
+	return p != nil && contains(p.AllowedCloudCredentials, id)
+}
+
+// Empty returns true if the filter propagates all keys.
+func (f MetadataFilter) Empty() bool {
+	return len(f.Allow) == 0 && len(f.Deny) == 0
+}
+
+// Allowed returns true if the key is propagated.
+func (f MetadataFilter) Allowed(key string) bool {
+	if matchesAny(f.Deny, key) {
+		return false
+	}
+	return len(f.Allow) == 0 || matchesAny(f.Allow, key)
+}
+
+// Apply returns the entries of values whose keys are propagated.
+func (f MetadataFilter) Apply(values map[string]string) map[string]string {
+	if values == nil || f.Empty() {
+		return values
+	}
+
+	ret := map[string]string{}
+	for k, v := range values {
+		if f.Allowed(k) {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if pattern == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMetadataFilterApply(t *testing.T) {
+	values := map[string]string{
+		"app":                                    "db",
+		"example.com/team":                       "storage",
+		"example.com/cost-center":                "42",
+		"stork.libopenstorage.org/snapshot-type": "local",
+	}
+
+	tests := []struct {
+		name     string
+		filter   MetadataFilter
+		expected map[string]string
+	}{
+		{
+			name:     "empty filter",
+			expected: values,
+		},
+		{
+			name:   "allow prefix",
+			filter: MetadataFilter{Allow: []string{"example.com/*"}},
+			expected: map[string]string{
+				"example.com/team":        "storage",
+				"example.com/cost-center": "42",
+			},
+		},
+		{
+			name:   "deny wins over allow",
+			filter: MetadataFilter{Allow: []string{"example.com/*", "app"}, Deny: []string{"example.com/cost-center"}},
+			expected: map[string]string{
+				"app":              "db",
+				"example.com/team": "storage",
+			},
+		},
+		{
+			name:   "deny only",
+			filter: MetadataFilter{Deny: []string{"stork.libopenstorage.org/*"}},
+			expected: map[string]string{
+				"app":                     "db",
+				"example.com/team":        "storage",
+				"example.com/cost-center": "42",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, tt.filter.Apply(values)); diff != "" {
+				t.Errorf("unexpected values (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseMetadataPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  bool
+	}{
+		{
+			name: "valid policy",
+			data: `{"kinds": {"VolumeSnapshot": {"down": {"allow": ["example.com/*"]}}}, "allowCloudSnapshots": true}`,
+		},
+		{
+			name: "wildcard inside a pattern",
+			data: `{"kinds": {"VolumeSnapshot": {"up": {"deny": ["example.*/team"]}}}}`,
+			err:  true,
+		},
+		{
+			name: "unknown field",
+			data: `{"kind": {}}`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMetadataPolicy([]byte(tt.data)); (err != nil) != tt.err {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
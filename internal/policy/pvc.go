@@ -0,0 +1,204 @@
+// Package policy restricts which storage classes and Portworx parameters tenants may use
+// and which labels and annotations are propagated between the clusters.
+package policy
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Portworx parameters tenants can override through PVC annotations. Portworx accepts
+// them with and without the "px/" prefix.
+const (
+	ParamRepl       = "repl"
+	ParamIOProfile  = "io_profile"
+	ParamPriorityIO = "priority_io"
+	ParamSharedV4   = "sharedv4"
+	ParamShared     = "shared"
+
+	paramPrefix = "px/"
+)
+
+// ViolationAction defines what happens to PVCs violating the policy.
+type ViolationAction string
+
+const (
+	// ViolationReject keeps the PVC from being synced to the host. Updates of PVCs which
+	// exist on the host are never rejected, offending values keep their host value.
+	ViolationReject ViolationAction = "reject"
+	// ViolationDrop removes offending parameter overrides and syncs the PVC. Storage
+	// classes which are not allowed are always rejected.
+	ViolationDrop ViolationAction = "drop"
+)
+
+// PVCPolicy is the policy applied to the PVCs of a vcluster.
+type PVCPolicy struct {
+	// AllowedStorageClasses are the host storage classes tenants may use. Empty allows all.
+	AllowedStorageClasses []string `json:"allowedStorageClasses,omitempty"`
+
+	// DefaultStorageClass is set on PVCs without a storage class.
+	DefaultStorageClass string `json:"defaultStorageClass,omitempty"`
+
+	// MaxReplicas is the highest replication factor tenants may request. 0 allows all.
+	MaxReplicas int `json:"maxReplicas,omitempty"`
+
+	// AllowedIOProfiles are the IO profiles tenants may request. Empty allows all.
+	AllowedIOProfiles []string `json:"allowedIOProfiles,omitempty"`
+
+	// AllowedPriorityIO are the IO priorities tenants may request. Empty allows all.
+	AllowedPriorityIO []string `json:"allowedPriorityIO,omitempty"`
+
+	// DenySharedVolumes rejects sharedv4 and shared volumes.
+	DenySharedVolumes bool `json:"denySharedVolumes,omitempty"`
+
+	// Defaults are parameters added to PVCs which don't set them.
+	Defaults map[string]string `json:"defaults,omitempty"`
+
+	// Overrides are parameters which replace the values set by tenants.
+	Overrides map[string]string `json:"overrides,omitempty"`
+
+	// OnViolation is the action taken on violations, defaults to ViolationReject.
+	OnViolation ViolationAction `json:"onViolation,omitempty"`
+}
+
+// ParsePVCPolicy parses a policy in YAML or JSON.
+func ParsePVCPolicy(data []byte) (*PVCPolicy, error) {
+	policy := &PVCPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, errors.Wrap(err, "parse pvc policy")
+	}
+
+	switch policy.OnViolation {
+	case "":
+		policy.OnViolation = ViolationReject
+	case ViolationReject, ViolationDrop:
+	default:
+		return nil, errors.Errorf("invalid onViolation %q", policy.OnViolation)
+	}
+	if policy.MaxReplicas < 0 {
+		return nil, errors.New("maxReplicas must not be negative")
+	}
+
+	return policy, nil
+}
+
+// Apply enforces the policy on a PVC about to be synced to the host. Defaults and
+// overrides are applied and violations are either dropped or returned as error,
+// depending on OnViolation. current is the host PVC on updates and nil on creates. On
+// updates offending values are never rejected, but reset to the values of current.
+func (p *PVCPolicy) Apply(pvc *corev1.PersistentVolumeClaim, current *corev1.PersistentVolumeClaim) error {
+	if current != nil {
+		// the storage class of a PVC is immutable
+		pvc.Spec.StorageClassName = current.Spec.StorageClassName
+	} else if pvc.Spec.StorageClassName == nil && p.DefaultStorageClass != "" {
+		storageClass := p.DefaultStorageClass
+		pvc.Spec.StorageClassName = &storageClass
+	}
+	if len(p.AllowedStorageClasses) > 0 && current == nil {
+		storageClass := ""
+		if pvc.Spec.StorageClassName != nil {
+			storageClass = *pvc.Spec.StorageClassName
+		}
+		if !contains(p.AllowedStorageClasses, storageClass) {
+			return errors.Errorf("storage class %q is not allowed", storageClass)
+		}
+	}
+
+	if pvc.Annotations == nil {
+		pvc.Annotations = map[string]string{}
+	}
+	for param, value := range p.Defaults {
+		if _, ok := lookupParam(pvc.Annotations, param); !ok {
+			pvc.Annotations[param] = value
+		}
+	}
+	for param, value := range p.Overrides {
+		deleteParam(pvc.Annotations, param)
+		pvc.Annotations[param] = value
+	}
+
+	var violations []error
+	for key, value := range pvc.Annotations {
+		if err := p.check(strings.TrimPrefix(key, paramPrefix), value); err != nil {
+			switch {
+			case current != nil:
+				if currentValue, ok := current.Annotations[key]; ok {
+					pvc.Annotations[key] = currentValue
+				} else {
+					delete(pvc.Annotations, key)
+				}
+			case p.OnViolation == ViolationDrop:
+				delete(pvc.Annotations, key)
+			default:
+				violations = append(violations, err)
+			}
+		}
+	}
+
+	return utilerrors.NewAggregate(violations)
+}
+
+func (p *PVCPolicy) check(param, value string) error {
+	switch param {
+	case ParamRepl:
+		if p.MaxReplicas == 0 {
+			return nil
+		}
+		repl, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.Errorf("invalid %s %q", param, value)
+		}
+		if repl > p.MaxReplicas {
+			return errors.Errorf("%s %d exceeds the maximum of %d", param, repl, p.MaxReplicas)
+		}
+	case ParamIOProfile:
+		if len(p.AllowedIOProfiles) > 0 && !contains(p.AllowedIOProfiles, value) {
+			return errors.Errorf("%s %q is not allowed", param, value)
+		}
+	case ParamPriorityIO:
+		if len(p.AllowedPriorityIO) > 0 && !contains(p.AllowedPriorityIO, value) {
+			return errors.Errorf("%s %q is not allowed", param, value)
+		}
+	case ParamSharedV4, ParamShared:
+		if !p.DenySharedVolumes {
+			return nil
+		}
+		// values Portworx might read as true are rejected as well
+		shared, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("invalid %s %q", param, value)
+		}
+		if shared {
+			return errors.Errorf("%s volumes are not allowed", param)
+		}
+	}
+
+	return nil
+}
+
+func lookupParam(annotations map[string]string, param string) (string, bool) {
+	if value, ok := annotations[param]; ok {
+		return value, true
+	}
+	value, ok := annotations[paramPrefix+param]
+	return value, ok
+}
+
+func deleteParam(annotations map[string]string, param string) {
+	delete(annotations, param)
+	delete(annotations, paramPrefix+param)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPVC(storageClass string, annotations map[string]string) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "data", Annotations: annotations},
+	}
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
+	return pvc
+}
+
+func TestPVCPolicyApply(t *testing.T) {
+	policy := &PVCPolicy{
+		AllowedStorageClasses: []string{"px-db", "px-shared"},
+		DefaultStorageClass:   "px-db",
+		MaxReplicas:           2,
+		AllowedIOProfiles:     []string{"db", "auto"},
+		DenySharedVolumes:     true,
+		Defaults:              map[string]string{ParamIOProfile: "auto"},
+		Overrides:             map[string]string{ParamPriorityIO: "low"},
+		OnViolation:           ViolationReject,
+	}
+	dropPolicy := *policy
+	dropPolicy.OnViolation = ViolationDrop
+
+	tests := []struct {
+		name         string
+		policy       *PVCPolicy
+		pvc          *corev1.PersistentVolumeClaim
+		current      *corev1.PersistentVolumeClaim
+		storageClass string
+		annotations  map[string]string
+		err          bool
+	}{
+		{
+			name:         "defaults and overrides",
+			policy:       policy,
+			pvc:          testPVC("", map[string]string{"px/" + ParamPriorityIO: "high"}),
+			storageClass: "px-db",
+			annotations:  map[string]string{ParamIOProfile: "auto", ParamPriorityIO: "low"},
+		},
+		{
+			name:         "default does not replace the tenant value",
+			policy:       policy,
+			pvc:          testPVC("px-db", map[string]string{ParamIOProfile: "db", ParamRepl: "2"}),
+			storageClass: "px-db",
+			annotations:  map[string]string{ParamIOProfile: "db", ParamRepl: "2", ParamPriorityIO: "low"},
+		},
+		{
+			name:   "storage class not allowed",
+			policy: &dropPolicy,
+			pvc:    testPVC("px-fast", nil),
+			err:    true,
+		},
+		{
+			name:   "reject replicas",
+			policy: policy,
+			pvc:    testPVC("px-db", map[string]string{ParamRepl: "3"}),
+			err:    true,
+		},
+		{
+			name:   "reject invalid replicas",
+			policy: policy,
+			pvc:    testPVC("px-db", map[string]string{ParamRepl: "three"}),
+			err:    true,
+		},
+		{
+			name:   "reject shared volumes",
+			policy: policy,
+			pvc:    testPVC("px-shared", map[string]string{ParamSharedV4: "True"}),
+			err:    true,
+		},
+		{
+			name:   "reject shared volumes with a numeric value",
+			policy: policy,
+			pvc:    testPVC("px-shared", map[string]string{"px/" + ParamShared: "1"}),
+			err:    true,
+		},
+		{
+			name:   "reject unparsable shared value",
+			policy: policy,
+			pvc:    testPVC("px-shared", map[string]string{ParamSharedV4: "yes"}),
+			err:    true,
+		},
+		{
+			name:         "allow disabled shared volumes",
+			policy:       policy,
+			pvc:          testPVC("px-shared", map[string]string{ParamSharedV4: "false"}),
+			storageClass: "px-shared",
+			annotations:  map[string]string{ParamSharedV4: "false", ParamIOProfile: "auto", ParamPriorityIO: "low"},
+		},
+		{
+			name:         "drop violations",
+			policy:       &dropPolicy,
+			pvc:          testPVC("px-db", map[string]string{ParamRepl: "3", ParamSharedV4: "t", ParamIOProfile: "sequential"}),
+			storageClass: "px-db",
+			annotations:  map[string]string{ParamPriorityIO: "low"},
+		},
+		{
+			name:         "revert violations on update",
+			policy:       policy,
+			pvc:          testPVC("px-fast", map[string]string{ParamRepl: "3", ParamSharedV4: "true", ParamIOProfile: "db"}),
+			current:      testPVC("px-db", map[string]string{ParamRepl: "2", ParamIOProfile: "auto"}),
+			storageClass: "px-db",
+			annotations:  map[string]string{ParamRepl: "2", ParamIOProfile: "db", ParamPriorityIO: "low"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Apply(tt.pvc, tt.current)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if tt.err {
+				return
+			}
+
+			storageClass := ""
+			if tt.pvc.Spec.StorageClassName != nil {
+				storageClass = *tt.pvc.Spec.StorageClassName
+			}
+			if storageClass != tt.storageClass {
+				t.Errorf("expected storage class %q, got %q", tt.storageClass, storageClass)
+			}
+			if diff := cmp.Diff(tt.annotations, tt.pvc.Annotations); diff != "" {
+				t.Errorf("unexpected annotations (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package provenance
+
+import (
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Provenance keys are set as labels as well as annotations on host objects. Labels make
+// host objects selectable per tenant, annotations always hold the full, untruncated value.
+const (
+	keyPrefix = "pxe.portworx.io/"
+
+	VClusterKey         = keyPrefix + "vcluster"
+	VirtualNamespaceKey = keyPrefix + "virtual-namespace"
+	VirtualNameKey      = keyPrefix + "virtual-name"
+	VirtualUIDKey       = keyPrefix + "virtual-uid"
+)
+
+// Owner identifies the virtual object a host object was created for.
+type Owner struct {
+	VCluster  string
+	Namespace string
+	Name      string
+	UID       types.UID
+}
+
+// OwnerOf returns the owner of a host object synced from the given virtual object.
+func OwnerOf(vObj client.Object) Owner {
+	return Owner{
+		VCluster:  translate.Suffix,
+		Namespace: vObj.GetNamespace(),
+		Name:      vObj.GetName(),
+		UID:       vObj.GetUID(),
+	}
+}
+
+// Labels returns the provenance labels for the owner. Values which are not valid label
+// values (e.g. names longer than 63 characters) are only kept in the annotations.
+func (o Owner) Labels() map[string]string {
+	ret := map[string]string{}
+	for k, v := range o.Annotations() {
+		if len(validation.IsValidLabelValue(v)) == 0 {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+// Annotations returns the provenance annotations for the owner.
+func (o Owner) Annotations() map[string]string {
+	ret := map[string]string{}
+	if o.VCluster != "" {
+		ret[VClusterKey] = o.VCluster
+	}
+	if o.Namespace != "" {
+		ret[VirtualNamespaceKey] = o.Namespace
+	}
+	if o.Name != "" {
+		ret[VirtualNameKey] = o.Name
+	}
+	if o.UID != "" {
+		ret[VirtualUIDKey] = string(o.UID)
+	}
+	return ret
+}
+
+// Stamp adds the provenance labels and annotations of the owner to the host object.
+func Stamp(pObj metav1.Object, owner Owner) {
+	pObj.SetLabels(Merge(pObj.GetLabels(), owner.Labels()))
+	pObj.SetAnnotations(Merge(pObj.GetAnnotations(), owner.Annotations()))
+}
+
+// Merge sets all values of from in to and returns to, allocating it if needed.
+func Merge(to, from map[string]string) map[string]string {
+	if to == nil {
+		to = map[string]string{}
+	}
+	for k, v := range from {
+		to[k] = v
+	}
+	return to
+}
+
+// FromObject reads the owner from a host object. Objects synced before provenance
+// metadata was introduced fall back to the generic vcluster translation markers.
+func FromObject(pObj client.Object) (Owner, bool) {
+	pLabels, pAnnotations := pObj.GetLabels(), pObj.GetAnnotations()
+	owner := Owner{
+		VCluster:  lookup(pAnnotations, pLabels, VClusterKey),
+		Namespace: lookup(pAnnotations, pLabels, VirtualNamespaceKey),
+		Name:      lookup(pAnnotations, pLabels, VirtualNameKey),
+		UID:       types.UID(lookup(pAnnotations, pLabels, VirtualUIDKey)),
+	}
+
+	if owner.VCluster == "" {
+		owner.VCluster = pLabels[translate.MarkerLabel]
+	}
+	if owner.Namespace == "" {
+		owner.Namespace = pAnnotations[translator.NamespaceAnnotation]
+	}
+	if owner.Name == "" {
+		owner.Name = pAnnotations[translator.NameAnnotation]
+	}
+
+	return owner, owner.VCluster != "" && owner.Name != ""
+}
+
+// Selector returns a label selector matching all host objects owned by the given vcluster.
+func Selector(vcluster string) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{VClusterKey: vcluster})
+}
+
+func lookup(annotations, labels map[string]string, key string) string {
+	if v := annotations[key]; v != "" {
+		return v
+	}
+	return labels[key]
+}
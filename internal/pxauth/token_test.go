@@ -0,0 +1,136 @@
+package pxauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+var now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestSharedSecretIssuer(t *testing.T) {
+	claims := Claims{
+		Subject: "vcluster/vcluster-a/a",
+		Name:    "a",
+		Roles:   []string{"system.user"},
+		Groups:  []string{"a"},
+	}
+	issuer := &sharedSecretIssuer{
+		issuer:       "operator.portworx.io",
+		sharedSecret: []byte("secret"),
+		now:          func() time.Time { return now },
+	}
+
+	token, expiry, err := issuer.Issue(claims, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !expiry.Equal(now.Add(24 * time.Hour)) {
+		t.Errorf("expected expiry %s, got %s", now.Add(24*time.Hour), expiry)
+	}
+
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 token segments, got %d", len(segments))
+	}
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(segments[0] + "." + segments[1]))
+	if encodeSegment(mac.Sum(nil)) != segments[2] {
+		t.Errorf("token signature doesn't match the shared secret")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	got := jwtClaims{}
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	expected := jwtClaims{
+		Claims:    claims,
+		Issuer:    "operator.portworx.io",
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(24 * time.Hour).Unix(),
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected claims (-want +got):\n%s", diff)
+	}
+}
+
+func TestSharedSecretIssuerErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		sharedSecret string
+		claims       Claims
+	}{
+		{
+			name:   "empty shared secret",
+			claims: Claims{Subject: "vcluster/vcluster-a/a"},
+		},
+		{
+			name:         "empty subject",
+			sharedSecret: "secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := NewSharedSecretIssuer("operator.portworx.io", []byte(tt.sharedSecret))
+			if _, _, err := issuer.Issue(tt.claims, time.Hour); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestFakeIssuer(t *testing.T) {
+	issuer := &FakeIssuer{Now: func() time.Time { return now }}
+
+	first := Claims{Subject: "a", Roles: []string{"system.user"}}
+	second := Claims{Subject: "b", Groups: []string{"b"}}
+	token, expiry, err := issuer.Issue(first, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "fake-token-a-1" {
+		t.Errorf("expected token fake-token-a-1, got %s", token)
+	}
+	if !expiry.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected expiry %s, got %s", now.Add(time.Hour), expiry)
+	}
+
+	token, expiry, err = issuer.Issue(second, 3*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "fake-token-b-2" {
+		t.Errorf("expected token fake-token-b-2, got %s", token)
+	}
+	if !expiry.Equal(now.Add(3 * time.Hour)) {
+		t.Errorf("expected expiry %s, got %s", now.Add(3*time.Hour), expiry)
+	}
+
+	if diff := cmp.Diff([]Claims{first, second}, issuer.Issued()); diff != "" {
+		t.Errorf("unexpected issued claims (-want +got):\n%s", diff)
+	}
+}
+
+func TestFakeIssuerError(t *testing.T) {
+	issuer := &FakeIssuer{Err: errors.New("unavailable")}
+
+	if _, _, err := issuer.Issue(Claims{Subject: "a"}, time.Hour); err == nil || err.Error() != "unavailable" {
+		t.Errorf("expected error unavailable, got %v", err)
+	}
+	if len(issuer.Issued()) != 0 {
+		t.Errorf("expected no issued claims, got %v", issuer.Issued())
+	}
+}
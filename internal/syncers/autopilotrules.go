@@ -0,0 +1,252 @@
+package syncers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	autopilotv1alpha1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/autopilot/v1alpha1"
+)
+
+func init() {
+	_ = autopilotv1alpha1.AddToScheme(plugin.Scheme)
+}
+
+// NewAutopilotRuleSyncer returns a syncer for tenant AutopilotRules. Selectors are
+// rewritten to only match host PVCs of the vcluster, only actions on single volumes are
+// allowed and volumes are never grown beyond maxSize, if set. Rules violating this are
+// not synced and an event is recorded on the virtual rule.
+func NewAutopilotRuleSyncer(ctx *synccontext.RegisterContext, maxSize resource.Quantity) syncer.Base {
+	targetNamespace := ctx.TargetNamespace
+	return &autopilotRuleSyncer{
+		Translator: translator.NewClusterTranslator(
+			ctx,
+			"autopilotrule",
+			&autopilotv1alpha1.AutopilotRule{},
+			func(vName string, _ client.Object) string {
+				return translate.PhysicalNameClusterScoped(vName, targetNamespace)
+			},
+		),
+		maxSize:         maxSize,
+		targetNamespace: targetNamespace,
+		virtualClient:   ctx.VirtualManager.GetClient(),
+		eventRecorder:   ctx.VirtualManager.GetEventRecorderFor("autopilotrule"),
+	}
+}
+
+type autopilotRuleSyncer struct {
+	translator.Translator
+
+	maxSize         resource.Quantity
+	targetNamespace string
+	virtualClient   client.Client
+	eventRecorder   record.EventRecorder
+}
+
+var _ syncer.Initializer = &autopilotRuleSyncer{}
+
+func (s *autopilotRuleSyncer) Init(ctx *synccontext.RegisterContext) error {
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		autopilotv1alpha1.SchemeGroupVersion.WithKind("AutopilotRule"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD AutopilotRule from physical cluster")
+	}
+
+	return nil
+}
+
+var _ syncer.ControllerModifier = &autopilotRuleSyncer{}
+
+// ModifyController re-syncs all rules when virtual namespaces change, as namespace
+// selectors are resolved to the matching namespaces.
+func (s *autopilotRuleSyncer) ModifyController(ctx *synccontext.RegisterContext, builder *builder.Builder) (*builder.Builder, error) {
+	return builder.Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(s.allRules)), nil
+}
+
+func (s *autopilotRuleSyncer) allRules(client.Object) []reconcile.Request {
+	vRules := &autopilotv1alpha1.AutopilotRuleList{}
+	if err := s.virtualClient.List(context.Background(), vRules); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, vRule := range vRules.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&vRule)})
+	}
+	return requests
+}
+
+func (s *autopilotRuleSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vRule := vObj.(*autopilotv1alpha1.AutopilotRule)
+	spec, err := s.translateSpec(ctx.Context, vRule)
+	if err != nil {
+		s.eventRecorder.Eventf(vRule, corev1.EventTypeWarning, "SyncError", "Rule is not synced: %v", err)
+		return ctrl.Result{}, nil
+	}
+
+	pRule := translateMetadata(s, vRule).(*autopilotv1alpha1.AutopilotRule)
+	pRule.Spec = *spec
+
+	ctx.Log.Infof("create physical autopilot rule %s", pRule.Name)
+	if err := ctx.PhysicalClient.Create(ctx.Context, pRule); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "create physical autopilot rule")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (s *autopilotRuleSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(pObj) || isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vRule := vObj.(*autopilotv1alpha1.AutopilotRule)
+	pRule := pObj.(*autopilotv1alpha1.AutopilotRule)
+
+	spec, err := s.translateSpec(ctx.Context, vRule)
+	if err != nil {
+		s.eventRecorder.Eventf(vRule, corev1.EventTypeWarning, "SyncError", "Rule is not synced: %v", err)
+		return syncer.DeleteObject(ctx, pRule)
+	}
+
+	changed, updatedAnnotations, updatedLabels := translateMetadataUpdate(s, vRule, pRule)
+	if !changed && equality.Semantic.DeepEqual(pRule.Spec, *spec) {
+		return ctrl.Result{}, nil
+	}
+
+	updated := pRule.DeepCopy()
+	updated.Annotations = updatedAnnotations
+	updated.Labels = updatedLabels
+	updated.Spec = *spec
+
+	ctx.Log.Infof("update physical autopilot rule %s", pRule.Name)
+	if err := ctx.PhysicalClient.Update(ctx.Context, updated); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "update physical autopilot rule")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// translateSpec returns the spec of the host rule for the virtual rule.
+func (s *autopilotRuleSyncer) translateSpec(ctx context.Context, vRule *autopilotv1alpha1.AutopilotRule) (*autopilotv1alpha1.AutopilotRuleSpec, error) {
+	spec := vRule.Spec.DeepCopy()
+	for i := range spec.Actions {
+		if err := s.limitAction(&spec.Actions[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	selector, err := s.translateSelector(ctx, vRule.Spec.Selector.LabelSelector, vRule.Spec.NamespaceSelector.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	spec.Selector = autopilotv1alpha1.RuleObjectSelector{LabelSelector: *selector}
+
+	// all host PVCs of the vcluster live in the vcluster namespace, the plugin refuses to
+	// run in multi-namespace mode
+	spec.NamespaceSelector = autopilotv1alpha1.RuleObjectSelector{
+		LabelSelector: metav1.LabelSelector{
+			MatchLabels: map[string]string{corev1.LabelMetadataName: s.targetNamespace},
+		},
+	}
+
+	return spec, nil
+}
+
+// limitAction rejects actions on anything but single volumes, e.g. storage pools, and
+// caps the size volumes are grown to.
+func (s *autopilotRuleSyncer) limitAction(action *autopilotv1alpha1.RuleAction) error {
+	if !strings.HasPrefix(action.Name, autopilotv1alpha1.VolumeActionPrefix) {
+		return errors.Errorf("action %s is not allowed, only volume actions are supported", action.Name)
+	}
+	if action.Name != autopilotv1alpha1.VolumeResizeAction || s.maxSize.IsZero() {
+		return nil
+	}
+
+	if action.Params == nil {
+		action.Params = map[string]string{}
+	}
+	if value, ok := action.Params[autopilotv1alpha1.ResizeMaxSizeParam]; ok {
+		maxSize, err := resource.ParseQuantity(value)
+		if err != nil {
+			return errors.Errorf("invalid %s %q", autopilotv1alpha1.ResizeMaxSizeParam, value)
+		}
+		if maxSize.Cmp(s.maxSize) <= 0 {
+			return nil
+		}
+	}
+
+	action.Params[autopilotv1alpha1.ResizeMaxSizeParam] = s.maxSize.String()
+	return nil
+}
+
+// translateSelector rewrites the label selector of a virtual rule to match the host PVCs
+// of the vcluster in the namespaces matching the namespace selector.
+func (s *autopilotRuleSyncer) translateSelector(
+	ctx context.Context,
+	vSelector metav1.LabelSelector,
+	vNamespaceSelector metav1.LabelSelector,
+) (*metav1.LabelSelector, error) {
+	pSelector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{translate.MarkerLabel: translate.Suffix},
+	}
+	for k, v := range vSelector.MatchLabels {
+		pSelector.MatchLabels[translator.ConvertLabelKey(k)] = v
+	}
+	for _, expr := range vSelector.MatchExpressions {
+		expr.Key = translator.ConvertLabelKey(expr.Key)
+		pSelector.MatchExpressions = append(pSelector.MatchExpressions, expr)
+	}
+
+	if len(vNamespaceSelector.MatchLabels) == 0 && len(vNamespaceSelector.MatchExpressions) == 0 {
+		return pSelector, nil
+	}
+
+	namespaceSelector, err := metav1.LabelSelectorAsSelector(&vNamespaceSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid namespace selector")
+	}
+	vNamespaces := &corev1.NamespaceList{}
+	if err := s.virtualClient.List(ctx, vNamespaces, client.MatchingLabelsSelector{Selector: namespaceSelector}); err != nil {
+		return nil, errors.Wrap(err, "list virtual namespaces")
+	}
+	if len(vNamespaces.Items) == 0 {
+		return nil, errors.New("namespace selector matches no namespace")
+	}
+
+	names := []string{}
+	for _, vNamespace := range vNamespaces.Items {
+		names = append(names, vNamespace.Name)
+	}
+	sort.Strings(names)
+	pSelector.MatchExpressions = append(pSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      translate.NamespaceLabel,
+		Operator: metav1.LabelSelectorOpIn,
+		Values:   names,
+	})
+
+	return pSelector, nil
+}
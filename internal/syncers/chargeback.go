@@ -0,0 +1,441 @@
+package syncers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/metrics"
+	"github.com/portworx/pxe-vcluster/internal/openstorage"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+const (
+	// ChargebackConfigMapNamespace and ChargebackConfigMapName locate the ConfigMap in the
+	// virtual cluster the chargeback report is written to.
+	ChargebackConfigMapNamespace = "kube-system"
+	ChargebackConfigMapName      = "pxe-chargeback"
+
+	chargebackReporterName = "px-chargeback"
+	chargebackJSONKey      = "report.json"
+	chargebackCSVKey       = "report.csv"
+
+	// the report ConfigMap lives in the tenant writable virtual cluster
+	chargebackNote = "Copy of the storage usage for tenants, which tenants can modify. " +
+		"Bill from the pxe_chargeback_* metrics of the plugin instead."
+)
+
+// Resources billed in the chargeback report.
+const (
+	chargebackPVCs           = "persistentvolumeclaims"
+	chargebackSnapshots      = "snapshots"
+	chargebackCloudSnapshots = "cloudsnapshots"
+)
+
+// NewChargebackReporter returns a controller which periodically aggregates the storage
+// usage of the vcluster per virtual namespace and publishes it as metrics and as JSON
+// and CSV report in the ConfigMap ChargebackConfigMapName. The metrics are authoritative,
+// the ConfigMap is a copy for tenants who can modify it. Used capacity of PVCs and local
+// snapshots is inspected through the SDK. Portworx doesn't report the size of a single
+// cloud snapshot, so all cloud snapshots of a volume are billed once with the usage of
+// the volume.
+func NewChargebackReporter(
+	ctx *synccontext.RegisterContext,
+	endpoint string,
+	tokenSecret string,
+	interval time.Duration,
+) syncer.Base {
+	return &chargebackReporter{
+		endpoint:    endpoint,
+		tokenSecret: tokenSecret,
+		interval:    interval,
+		log:         log.New(chargebackReporterName),
+	}
+}
+
+type chargebackReporter struct {
+	endpoint    string
+	tokenSecret string
+	interval    time.Duration
+	log         log.Logger
+
+	sdk            *openstorage.Client
+	virtualClient  client.Client
+	physicalClient client.Client
+	targetNs       string
+}
+
+// chargebackReport is the report written to the ConfigMap.
+type chargebackReport struct {
+	VCluster    string            `json:"vcluster"`
+	GeneratedAt metav1.Time       `json:"generatedAt"`
+	Note        string            `json:"note"`
+	Entries     []chargebackEntry `json:"entries"`
+	Totals      []chargebackEntry `json:"totals"`
+}
+
+// chargebackEntry is the usage of a resource in a namespace, or in the whole vcluster
+// for totals.
+type chargebackEntry struct {
+	Namespace        string `json:"namespace,omitempty"`
+	Resource         string `json:"resource"`
+	StorageClass     string `json:"storageClass,omitempty"`
+	Count            int64  `json:"count"`
+	ProvisionedBytes uint64 `json:"provisionedBytes"`
+	UsedBytes        uint64 `json:"usedBytes"`
+}
+
+type chargebackKey struct {
+	namespace    string
+	resource     string
+	storageClass string
+}
+
+func (r *chargebackReporter) Name() string {
+	return chargebackReporterName
+}
+
+var _ syncer.ControllerStarter = &chargebackReporter{}
+
+func (r *chargebackReporter) Register(ctx *synccontext.RegisterContext) error {
+	r.virtualClient = ctx.VirtualManager.GetClient()
+	r.physicalClient = ctx.PhysicalManager.GetClient()
+	r.targetNs = ctx.TargetNamespace
+
+	sdk, err := newSDKClient(ctx, r.endpoint, r.tokenSecret)
+	if err != nil {
+		return err
+	}
+	r.sdk = sdk
+
+	go func() {
+		wait.UntilWithContext(ctx.Context, func(ctx context.Context) {
+			if err := r.report(ctx); err != nil {
+				r.log.Errorf("error writing chargeback report: %v", err)
+			}
+		}, r.interval)
+		_ = r.sdk.Close()
+	}()
+
+	return nil
+}
+
+func (r *chargebackReporter) report(ctx context.Context) error {
+	usage := map[chargebackKey]*chargebackEntry{}
+	add := func(key chargebackKey, provisioned, used uint64) {
+		entry, ok := usage[key]
+		if !ok {
+			entry = &chargebackEntry{
+				Namespace:    key.namespace,
+				Resource:     key.resource,
+				StorageClass: key.storageClass,
+			}
+			usage[key] = entry
+		}
+		entry.Count++
+		entry.ProvisionedBytes += provisioned
+		entry.UsedBytes += used
+	}
+
+	if err := r.collectPVCs(ctx, add); err != nil {
+		return err
+	}
+	if err := r.collectSnapshots(ctx, add); err != nil {
+		return err
+	}
+
+	report := newChargebackReport(usage)
+	r.setMetrics(report)
+	return r.writeReport(ctx, report)
+}
+
+func (r *chargebackReporter) collectPVCs(ctx context.Context, add func(chargebackKey, uint64, uint64)) error {
+	vPVCs := &corev1.PersistentVolumeClaimList{}
+	if err := r.virtualClient.List(ctx, vPVCs); err != nil {
+		return errors.Wrap(err, "list virtual persistent volume claims")
+	}
+
+	for _, vPVC := range vPVCs.Items {
+		if vPVC.Status.Phase != corev1.ClaimBound {
+			continue
+		}
+
+		storageClass := ""
+		if vPVC.Spec.StorageClassName != nil {
+			storageClass = *vPVC.Spec.StorageClassName
+		}
+
+		var provisioned uint64
+		if capacity, ok := vPVC.Status.Capacity[corev1.ResourceStorage]; ok && capacity.Value() > 0 {
+			provisioned = uint64(capacity.Value())
+		}
+		used, err := r.pvcUsage(ctx, &vPVC)
+		if err != nil {
+			r.log.Infof("error getting usage of persistent volume claim %s/%s: %v", vPVC.Namespace, vPVC.Name, err)
+		}
+
+		add(chargebackKey{
+			namespace:    vPVC.Namespace,
+			resource:     chargebackPVCs,
+			storageClass: storageClass,
+		}, provisioned, used)
+	}
+
+	return nil
+}
+
+// pvcUsage returns the used bytes of the Portworx volume of a virtual PVC, which is read
+// from the host, as tenants can modify the status annotations of virtual PVCs.
+func (r *chargebackReporter) pvcUsage(ctx context.Context, vPVC *corev1.PersistentVolumeClaim) (uint64, error) {
+	volumeID, err := r.pvcVolumeID(ctx, vPVC)
+	if err != nil || volumeID == "" {
+		return 0, err
+	}
+
+	volume, err := r.sdk.InspectVolume(ctx, volumeID)
+	if err != nil {
+		return 0, err
+	}
+	return volume.Usage, nil
+}
+
+// pvcVolumeID returns the id of the Portworx volume of a virtual PVC or an empty string
+// for volumes of other drivers.
+func (r *chargebackReporter) pvcVolumeID(ctx context.Context, vPVC *corev1.PersistentVolumeClaim) (string, error) {
+	pPVC := &corev1.PersistentVolumeClaim{}
+	if err := r.physicalClient.Get(ctx, client.ObjectKey{
+		Namespace: r.targetNs,
+		Name:      translate.PhysicalName(vPVC.Name, vPVC.Namespace),
+	}, pPVC); err != nil {
+		return "", errors.Wrap(client.IgnoreNotFound(err), "get physical persistent volume claim")
+	}
+	if pPVC.Spec.VolumeName == "" {
+		return "", nil
+	}
+
+	pv := &corev1.PersistentVolume{}
+	if err := r.physicalClient.Get(ctx, client.ObjectKey{Name: pPVC.Spec.VolumeName}, pv); err != nil {
+		return "", errors.Wrap(client.IgnoreNotFound(err), "get persistent volume")
+	}
+	return portworxVolumeID(pv), nil
+}
+
+func (r *chargebackReporter) collectSnapshots(ctx context.Context, add func(chargebackKey, uint64, uint64)) error {
+	pSnapshots := &snapshotv1.VolumeSnapshotList{}
+	if err := r.physicalClient.List(ctx, pSnapshots, client.InNamespace(r.targetNs)); err != nil {
+		// snapshots are optional, the host may lack the CRD
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return errors.Wrap(err, "list physical volume snapshots")
+	}
+
+	// volumes whose cloud snapshots were billed already
+	billedVolumes := map[string]bool{}
+	for i := range pSnapshots.Items {
+		pSnapshot := &pSnapshots.Items[i]
+		owner, ok := provenance.FromObject(pSnapshot)
+		if !ok || owner.VCluster != translate.Suffix {
+			continue
+		}
+
+		resource, used, err := r.snapshotUsage(ctx, pSnapshot, billedVolumes)
+		if err != nil {
+			r.log.Infof("error getting size of snapshot %s: %v", pSnapshot.Name, err)
+		}
+		add(chargebackKey{namespace: owner.Namespace, resource: resource}, 0, used)
+	}
+
+	return nil
+}
+
+// snapshotUsage returns the resource a snapshot is billed as and its size. Cloud snapshots
+// are billed with the usage of their source volume, unless billedVolumes contains it.
+func (r *chargebackReporter) snapshotUsage(
+	ctx context.Context,
+	pSnapshot *snapshotv1.VolumeSnapshot,
+	billedVolumes map[string]bool,
+) (string, uint64, error) {
+	if pSnapshot.Spec.SnapshotDataName == "" {
+		return chargebackSnapshots, 0, nil
+	}
+
+	pData := &snapshotv1.VolumeSnapshotData{}
+	if err := r.physicalClient.Get(ctx, client.ObjectKey{Name: pSnapshot.Spec.SnapshotDataName}, pData); err != nil {
+		return chargebackSnapshots, 0, errors.Wrap(err, "get volume snapshot data")
+	}
+
+	pxSnapshot := pData.Spec.PortworxSnapshot
+	if pxSnapshot == nil {
+		return chargebackSnapshots, 0, nil
+	}
+	if pxSnapshot.SnapshotType != snapshotv1.PortworxSnapshotTypeCloud {
+		volume, err := r.sdk.InspectVolume(ctx, pxSnapshot.SnapshotID)
+		if err != nil {
+			return chargebackSnapshots, 0, err
+		}
+		return chargebackSnapshots, volume.Usage, nil
+	}
+
+	// cloud snapshots are billed with the usage of their source volume
+	if pData.Spec.PersistentVolumeRef == nil {
+		return chargebackCloudSnapshots, 0, nil
+	}
+	pv := &corev1.PersistentVolume{}
+	if err := r.physicalClient.Get(ctx, client.ObjectKey{Name: pData.Spec.PersistentVolumeRef.Name}, pv); err != nil {
+		return chargebackCloudSnapshots, 0, errors.Wrap(err, "get persistent volume")
+	}
+	volumeID := portworxVolumeID(pv)
+	if volumeID == "" || billedVolumes[volumeID] {
+		return chargebackCloudSnapshots, 0, nil
+	}
+	volume, err := r.sdk.InspectVolume(ctx, volumeID)
+	if err != nil {
+		return chargebackCloudSnapshots, 0, err
+	}
+	billedVolumes[volumeID] = true
+
+	return chargebackCloudSnapshots, volume.Usage, nil
+}
+
+func newChargebackReport(usage map[chargebackKey]*chargebackEntry) *chargebackReport {
+	report := &chargebackReport{
+		VCluster:    translate.Suffix,
+		GeneratedAt: metav1.Now(),
+		Note:        chargebackNote,
+		Entries:     []chargebackEntry{},
+		Totals:      []chargebackEntry{},
+	}
+
+	totals := map[chargebackKey]*chargebackEntry{}
+	for _, entry := range usage {
+		report.Entries = append(report.Entries, *entry)
+
+		key := chargebackKey{resource: entry.Resource, storageClass: entry.StorageClass}
+		total, ok := totals[key]
+		if !ok {
+			total = &chargebackEntry{Resource: entry.Resource, StorageClass: entry.StorageClass}
+			totals[key] = total
+		}
+		total.Count += entry.Count
+		total.ProvisionedBytes += entry.ProvisionedBytes
+		total.UsedBytes += entry.UsedBytes
+	}
+	for _, total := range totals {
+		report.Totals = append(report.Totals, *total)
+	}
+
+	sortChargebackEntries(report.Entries)
+	sortChargebackEntries(report.Totals)
+	return report
+}
+
+func sortChargebackEntries(entries []chargebackEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.StorageClass < b.StorageClass
+	})
+}
+
+func (r *chargebackReporter) setMetrics(report *chargebackReport) {
+	metrics.ChargebackCount.Reset()
+	metrics.ChargebackProvisionedBytes.Reset()
+	metrics.ChargebackUsedBytes.Reset()
+	for _, entry := range report.Entries {
+		labels := []string{report.VCluster, entry.Namespace, entry.Resource, entry.StorageClass}
+		metrics.ChargebackCount.WithLabelValues(labels...).Set(float64(entry.Count))
+		metrics.ChargebackProvisionedBytes.WithLabelValues(labels...).Set(float64(entry.ProvisionedBytes))
+		metrics.ChargebackUsedBytes.WithLabelValues(labels...).Set(float64(entry.UsedBytes))
+	}
+}
+
+func (r *chargebackReporter) writeReport(ctx context.Context, report *chargebackReport) error {
+	jsonReport, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal report")
+	}
+	csvReport, err := chargebackCSV(report)
+	if err != nil {
+		return errors.Wrap(err, "write csv report")
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := r.virtualClient.Get(ctx, client.ObjectKey{
+		Namespace: ChargebackConfigMapNamespace,
+		Name:      ChargebackConfigMapName,
+	}, configMap); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return errors.Wrap(err, "get chargeback config map")
+		}
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ChargebackConfigMapNamespace,
+				Name:      ChargebackConfigMapName,
+			},
+			Data: map[string]string{
+				chargebackJSONKey: string(jsonReport),
+				chargebackCSVKey:  csvReport,
+			},
+		}
+		return errors.Wrap(r.virtualClient.Create(ctx, configMap), "create chargeback config map")
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[chargebackJSONKey] = string(jsonReport)
+	configMap.Data[chargebackCSVKey] = csvReport
+	return errors.Wrap(r.virtualClient.Update(ctx, configMap), "update chargeback config map")
+}
+
+// chargebackCSV writes the report as CSV. Totals of the vcluster use the namespace "*".
+func chargebackCSV(report *chargebackReport) (string, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	_ = w.Write([]string{"vcluster", "namespace", "resource", "storage_class", "count", "provisioned_bytes", "used_bytes"})
+
+	write := func(namespace string, entry chargebackEntry) {
+		_ = w.Write([]string{
+			report.VCluster,
+			namespace,
+			entry.Resource,
+			entry.StorageClass,
+			strconv.FormatInt(entry.Count, 10),
+			strconv.FormatUint(entry.ProvisionedBytes, 10),
+			strconv.FormatUint(entry.UsedBytes, 10),
+		})
+	}
+	for _, entry := range report.Entries {
+		write(entry.Namespace, entry)
+	}
+	for _, entry := range report.Totals {
+		write("*", entry)
+	}
+
+	w.Flush()
+	return buf.String(), w.Error()
+}
@@ -0,0 +1,141 @@
+package syncers
+
+import (
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storkv1alpha1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/stork/v1alpha1"
+	"github.com/portworx/pxe-vcluster/internal/config"
+)
+
+// RemoteClusterAnnotation names the ClusterPair of the admin a tenant ClusterPair pairs
+// with. If not set, the name of the tenant ClusterPair is used.
+const RemoteClusterAnnotation = "pxe.portworx.io/remote-cluster"
+
+// clusterPairResyncInterval is the interval in which changes to the ClusterPairs of the
+// admin are picked up, as they live outside of the watched namespace.
+const clusterPairResyncInterval = 5 * time.Minute
+
+// NewClusterPairSyncer returns a syncer for Stork ClusterPairs. Tenants only reference
+// the remote clusters set up by the admin as ClusterPairs in adminNamespace by name; the
+// host ClusterPair gets the config and options of the admin, anything set by the tenant
+// is ignored. The pairing status is synced up.
+func NewClusterPairSyncer(ctx *synccontext.RegisterContext, adminNamespace string) syncer.Base {
+	return &clusterPairSyncer{
+		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, config.ClusterPairSyncerName, &storkv1alpha1.ClusterPair{}),
+		adminNamespace:       adminNamespace,
+		physicalReader:       ctx.PhysicalManager.GetAPIReader(),
+	}
+}
+
+type clusterPairSyncer struct {
+	translator.NamespacedTranslator
+
+	adminNamespace string
+	physicalReader client.Reader
+}
+
+var _ syncer.Initializer = &clusterPairSyncer{}
+
+func (s *clusterPairSyncer) Init(ctx *synccontext.RegisterContext) error {
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		storkv1alpha1.SchemeGroupVersion.WithKind("ClusterPair"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD ClusterPair from physical cluster")
+	}
+
+	return nil
+}
+
+func (s *clusterPairSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vPair := vObj.(*storkv1alpha1.ClusterPair)
+	spec, err := s.remoteSpec(ctx, vPair)
+	if err != nil || spec == nil {
+		return ctrl.Result{}, err
+	}
+
+	pPair := translateMetadata(s, vPair).(*storkv1alpha1.ClusterPair)
+	pPair.Spec = *spec
+	pPair.Status = storkv1alpha1.ClusterPairStatus{}
+
+	return s.SyncDownCreate(ctx, vObj, pPair)
+}
+
+func (s *clusterPairSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(pObj) || isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vPair := vObj.(*storkv1alpha1.ClusterPair)
+	pPair := pObj.(*storkv1alpha1.ClusterPair)
+
+	if !equality.Semantic.DeepEqual(vPair.Status, pPair.Status) {
+		updated := vPair.DeepCopy()
+		updated.Status = pPair.Status
+		ctx.Log.Infof("update virtual cluster pair status %s/%s", vPair.Namespace, vPair.Name)
+		if err := ctx.VirtualClient.Update(ctx.Context, updated); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "update virtual cluster pair status")
+		}
+	}
+
+	spec, err := s.remoteSpec(ctx, vPair)
+	if err != nil || spec == nil {
+		return ctrl.Result{RequeueAfter: clusterPairResyncInterval}, err
+	}
+
+	var updated *storkv1alpha1.ClusterPair
+	changed, updatedAnnotations, updatedLabels := translateMetadataUpdate(s, vPair, pPair)
+	if changed {
+		updated = pPair.DeepCopy()
+		updated.Annotations = updatedAnnotations
+		updated.Labels = updatedLabels
+	}
+	if !equality.Semantic.DeepEqual(pPair.Spec, *spec) {
+		if updated == nil {
+			updated = pPair.DeepCopy()
+		}
+		updated.Spec = *spec
+	}
+
+	result, err := s.SyncDownUpdate(ctx, vObj, updated)
+	if err != nil {
+		return result, err
+	}
+	return ctrl.Result{RequeueAfter: clusterPairResyncInterval}, nil
+}
+
+// remoteSpec returns the spec of the ClusterPair of the admin the virtual ClusterPair
+// references. If the admin has no such ClusterPair, an event is recorded and nil is
+// returned.
+func (s *clusterPairSyncer) remoteSpec(ctx *synccontext.SyncContext, vPair *storkv1alpha1.ClusterPair) (*storkv1alpha1.ClusterPairSpec, error) {
+	name := vPair.Annotations[RemoteClusterAnnotation]
+	if name == "" {
+		name = vPair.Name
+	}
+
+	adminPair := &storkv1alpha1.ClusterPair{}
+	err := s.physicalReader.Get(ctx.Context, client.ObjectKey{Namespace: s.adminNamespace, Name: name}, adminPair)
+	if kerrors.IsNotFound(err) {
+		s.EventRecorder().Eventf(vPair, corev1.EventTypeWarning, "SyncError", "Remote cluster %s is not available", name)
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "get remote cluster %s", name)
+	}
+
+	return adminPair.Spec.DeepCopy(), nil
+}
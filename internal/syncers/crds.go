@@ -0,0 +1,131 @@
+package syncers
+
+import (
+	"context"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// NewCRDSyncer returns a controller which watches the given CRDs in the host cluster and
+// keeps the versions and names of their copies in the virtual cluster up to date, e.g.
+// after an upgrade of Stork or Portworx changed the host CRDs.
+func NewCRDSyncer(ctx *synccontext.RegisterContext, crdNames ...string) syncer.Base {
+	names := map[string]bool{}
+	for _, name := range crdNames {
+		names[name] = true
+	}
+
+	return &crdSyncer{
+		names: names,
+		log:   log.New("crd-syncer"),
+	}
+}
+
+type crdSyncer struct {
+	names map[string]bool
+	log   log.Logger
+
+	virtualClient  client.Client
+	physicalClient client.Client
+}
+
+func (s *crdSyncer) Name() string {
+	return "crd-syncer"
+}
+
+var _ syncer.ControllerStarter = &crdSyncer{}
+
+func (s *crdSyncer) Register(ctx *synccontext.RegisterContext) error {
+	s.virtualClient = ctx.VirtualManager.GetClient()
+	s.physicalClient = ctx.PhysicalManager.GetClient()
+
+	return ctrl.NewControllerManagedBy(ctx.PhysicalManager).
+		Named(s.Name()).
+		For(&apiextensionsv1.CustomResourceDefinition{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return s.names[obj.GetName()]
+			}),
+		)).
+		Complete(s)
+}
+
+func (s *crdSyncer) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	pCRD := &apiextensionsv1.CustomResourceDefinition{}
+	if err := s.physicalClient.Get(ctx, req.NamespacedName, pCRD); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	vCRD := &apiextensionsv1.CustomResourceDefinition{}
+	if err := s.virtualClient.Get(ctx, req.NamespacedName, vCRD); err != nil {
+		// the CRD is created in the virtual cluster by the syncers depending on it
+		if kerrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "get virtual crd")
+	}
+
+	updated := translateCRDUpdate(pCRD, vCRD)
+	if updated == nil {
+		return ctrl.Result{}, nil
+	}
+
+	s.log.Infof("update virtual crd %s, because host crd has changed: %s", vCRD.Name, cmp.Diff(vCRD.Spec, updated.Spec))
+	if err := s.virtualClient.Update(ctx, updated); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "update virtual crd")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func translateCRDUpdate(pCRD, vCRD *apiextensionsv1.CustomResourceDefinition) *apiextensionsv1.CustomResourceDefinition {
+	versions := crdVersions(pCRD, vCRD)
+	if equality.Semantic.DeepEqual(versions, vCRD.Spec.Versions) &&
+		equality.Semantic.DeepEqual(pCRD.Spec.Names, vCRD.Spec.Names) {
+		return nil
+	}
+
+	updated := vCRD.DeepCopy()
+	updated.Spec.Versions = versions
+	updated.Spec.Names = pCRD.Spec.Names
+	return updated
+}
+
+// crdVersions returns the versions of the host CRD. Versions which were dropped on the
+// host but are still stored in the virtual cluster are kept without being served, as the
+// api server rejects removing stored versions.
+func crdVersions(pCRD, vCRD *apiextensionsv1.CustomResourceDefinition) []apiextensionsv1.CustomResourceDefinitionVersion {
+	versions := []apiextensionsv1.CustomResourceDefinitionVersion{}
+	hostVersions := map[string]bool{}
+	for _, version := range pCRD.Spec.Versions {
+		versions = append(versions, *version.DeepCopy())
+		hostVersions[version.Name] = true
+	}
+
+	for _, storedVersion := range vCRD.Status.StoredVersions {
+		if hostVersions[storedVersion] {
+			continue
+		}
+
+		for _, version := range vCRD.Spec.Versions {
+			if version.Name == storedVersion {
+				version = *version.DeepCopy()
+				version.Served = false
+				version.Storage = false
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	return versions
+}
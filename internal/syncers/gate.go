@@ -0,0 +1,162 @@
+package syncers
+
+import (
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/portworx/pxe-vcluster/internal/config"
+)
+
+// NewCRDGate wraps a syncer which depends on a CRD of the host cluster. If the CRD is
+// missing, the syncer either fails the plugin or, with the wait policy, stays disabled
+// until the CRD shows up on the host and is then started.
+func NewCRDGate(cfg *config.Config, s syncer.Base, gvk schema.GroupVersionKind) syncer.Base {
+	return &crdGate{
+		syncer:   s,
+		gvk:      gvk,
+		policy:   cfg.MissingCRDPolicy,
+		interval: cfg.CRDPollInterval,
+		log:      log.New(s.Name() + "-gate"),
+	}
+}
+
+type crdGate struct {
+	syncer   syncer.Base
+	gvk      schema.GroupVersionKind
+	policy   config.MissingCRDPolicy
+	interval time.Duration
+	log      log.Logger
+
+	// active is true if the CRD was found during Init
+	active bool
+}
+
+func (g *crdGate) Name() string {
+	return g.syncer.Name()
+}
+
+var _ syncer.Initializer = &crdGate{}
+
+func (g *crdGate) Init(ctx *synccontext.RegisterContext) error {
+	exists, err := g.kindExists(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if g.policy != config.MissingCRDPolicyWait {
+			return errors.Errorf("kind %s is not available in the host cluster", g.gvk.String())
+		}
+
+		g.log.Infof(
+			"Syncer %s is disabled, because kind %s is not available in the host cluster. Checking again every %s",
+			g.Name(),
+			g.gvk.String(),
+			g.interval,
+		)
+		return nil
+	}
+
+	g.active = true
+	return g.initSyncer(ctx)
+}
+
+var _ syncer.IndicesRegisterer = &crdGate{}
+
+func (g *crdGate) RegisterIndices(ctx *synccontext.RegisterContext) error {
+	if !g.active {
+		return nil
+	}
+
+	return g.registerIndices(ctx)
+}
+
+var _ syncer.ControllerStarter = &crdGate{}
+
+func (g *crdGate) Register(ctx *synccontext.RegisterContext) error {
+	if g.active {
+		return g.startSyncer(ctx)
+	}
+
+	go g.waitForCRD(ctx)
+	return nil
+}
+
+// waitForCRD polls the host cluster until the CRD is available and starts the syncer
+// afterwards.
+func (g *crdGate) waitForCRD(ctx *synccontext.RegisterContext) {
+	err := wait.PollUntil(g.interval, func() (bool, error) {
+		exists, err := g.kindExists(ctx)
+		if err != nil {
+			g.log.Errorf("error checking for kind %s: %v", g.gvk.String(), err)
+			return false, nil
+		}
+		return exists, nil
+	}, ctx.Context.Done())
+	if err != nil {
+		// context is done
+		return
+	}
+
+	g.log.Infof("Kind %s is now available in the host cluster, starting syncer %s", g.gvk.String(), g.Name())
+	if err := g.initSyncer(ctx); err != nil {
+		g.log.Errorf("error initializing syncer %s: %v", g.Name(), err)
+		return
+	}
+
+	// indices can't be added to informers which are already running. Starting the syncer
+	// without them would break its name lookups, so it stays disabled until the plugin is
+	// restarted and registers the indices before the caches start.
+	if err := g.registerIndices(ctx); err != nil {
+		g.log.Errorf(
+			"error registering indices for syncer %s, restart the plugin to start it: %v",
+			g.Name(),
+			err,
+		)
+		return
+	}
+
+	if err := g.startSyncer(ctx); err != nil {
+		g.log.Errorf("error starting syncer %s: %v", g.Name(), err)
+	}
+}
+
+func (g *crdGate) kindExists(ctx *synccontext.RegisterContext) (bool, error) {
+	exists, err := translate.KindExists(ctx.PhysicalManager.GetConfig(), g.gvk)
+	if err != nil {
+		return false, errors.Wrapf(err, "check host cluster kind %s", g.gvk.String())
+	}
+	return exists, nil
+}
+
+func (g *crdGate) initSyncer(ctx *synccontext.RegisterContext) error {
+	if initializer, ok := g.syncer.(syncer.Initializer); ok {
+		return initializer.Init(ctx)
+	}
+	return nil
+}
+
+func (g *crdGate) registerIndices(ctx *synccontext.RegisterContext) error {
+	if indicesRegisterer, ok := g.syncer.(syncer.IndicesRegisterer); ok {
+		return indicesRegisterer.RegisterIndices(ctx)
+	}
+	return nil
+}
+
+func (g *crdGate) startSyncer(ctx *synccontext.RegisterContext) error {
+	if realSyncer, ok := g.syncer.(syncer.Syncer); ok {
+		g.log.Infof("Start syncer %s", g.Name())
+		return syncer.RegisterSyncer(ctx, realSyncer)
+	}
+	if controllerStarter, ok := g.syncer.(syncer.ControllerStarter); ok {
+		return controllerStarter.Register(ctx)
+	}
+	return nil
+}
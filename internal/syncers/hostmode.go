@@ -0,0 +1,95 @@
+package syncers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/portworx/pxe-vcluster/internal/hostguard"
+)
+
+// HostModeKey is the key of the host mode ConfigMap holding the mode, either "active",
+// "paused" or "dry-run".
+const HostModeKey = "mode"
+
+// NewHostModeController returns a controller which switches the mode of the host guard
+// at runtime according to the ConfigMap of the given name in the host namespace of the
+// vcluster. Without the ConfigMap or its mode key, the mode set through the environment
+// applies. Switching to active mode resyncs all objects of the syncers wrapped by
+// NewResyncer and of the snapshot bridges, so skipped writes are performed.
+func NewHostModeController(ctx *synccontext.RegisterContext, modes *hostguard.Switch, configMapName string) syncer.Base {
+	return &hostModeController{
+		modes:         modes,
+		configMapName: configMapName,
+		log:           log.New("hostmode"),
+	}
+}
+
+type hostModeController struct {
+	modes         *hostguard.Switch
+	configMapName string
+	log           log.Logger
+
+	physicalClient client.Client
+}
+
+func (c *hostModeController) Name() string {
+	return "hostmode"
+}
+
+var _ syncer.ControllerStarter = &hostModeController{}
+
+func (c *hostModeController) Register(ctx *synccontext.RegisterContext) error {
+	c.physicalClient = ctx.PhysicalManager.GetClient()
+
+	return ctrl.NewControllerManagedBy(ctx.PhysicalManager).
+		Named(c.Name()).
+		For(&corev1.ConfigMap{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return obj.GetName() == c.configMapName
+			}),
+		)).
+		Complete(c)
+}
+
+func (c *hostModeController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := c.physicalClient.Get(ctx, req.NamespacedName, configMap); err != nil {
+		if kerrors.IsNotFound(err) {
+			c.modes.Reset()
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	// ConfigMaps synced from the vcluster are controlled by tenants, not by the admin
+	if configMap.Labels[translate.MarkerLabel] != "" {
+		c.log.Infof("ignore config map %s/%s, because it is synced from the vcluster", configMap.Namespace, configMap.Name)
+		return ctrl.Result{}, nil
+	}
+
+	value, ok := configMap.Data[HostModeKey]
+	if !ok {
+		c.modes.Reset()
+		return ctrl.Result{}, nil
+	}
+	mode, err := hostguard.ParseMode(strings.TrimSpace(value))
+	if err != nil {
+		// the mode is kept until the config map is fixed, retrying won't help
+		c.log.Errorf("invalid mode in config map %s/%s: %v", configMap.Namespace, configMap.Name, err)
+		return ctrl.Result{}, nil
+	}
+
+	c.modes.Set(mode)
+	return ctrl.Result{}, nil
+}
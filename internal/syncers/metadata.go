@@ -0,0 +1,50 @@
+package syncers
+
+import (
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/portworx/pxe-vcluster/internal/policy"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+// filterMetadata returns a copy of obj without the labels and annotations the filter
+// doesn't propagate, or obj itself if the filter propagates everything. Host annotations
+// of removed keys are dropped on the next update, like any other unmanaged annotation.
+func filterMetadata(filter policy.MetadataFilter, obj client.Object) client.Object {
+	if filter.Empty() {
+		return obj
+	}
+
+	filtered := obj.DeepCopyObject().(client.Object)
+	filtered.SetLabels(filter.Apply(obj.GetLabels()))
+	filtered.SetAnnotations(filter.Apply(obj.GetAnnotations()))
+	return filtered
+}
+
+// translateMetadata translates the metadata of the virtual object and stamps the
+// result with the provenance of the virtual object.
+func translateMetadata(t translator.MetadataTranslator, vObj client.Object) client.Object {
+	pObj := t.TranslateMetadata(vObj)
+	provenance.Stamp(pObj, provenance.OwnerOf(vObj))
+	return pObj
+}
+
+// translateMetadataUpdate works like TranslateMetadataUpdate, but keeps the provenance
+// labels and annotations which would otherwise be dropped from the physical object.
+func translateMetadataUpdate(
+	t translator.MetadataTranslator,
+	vObj client.Object,
+	pObj client.Object,
+) (bool, map[string]string, map[string]string) {
+	_, updatedAnnotations, updatedLabels := t.TranslateMetadataUpdate(vObj, pObj)
+
+	owner := provenance.OwnerOf(vObj)
+	updatedAnnotations = provenance.Merge(updatedAnnotations, owner.Annotations())
+	updatedLabels = provenance.Merge(updatedLabels, owner.Labels())
+
+	changed := !equality.Semantic.DeepEqual(updatedAnnotations, pObj.GetAnnotations()) ||
+		!equality.Semantic.DeepEqual(updatedLabels, pObj.GetLabels())
+	return changed, updatedAnnotations, updatedLabels
+}
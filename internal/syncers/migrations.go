@@ -0,0 +1,370 @@
+package syncers
+
+import (
+	"strings"
+
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storkv1alpha1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/stork/v1alpha1"
+)
+
+// NewMigrationSyncer returns a syncer for Stork Migrations. The migrated namespace is
+// rewritten to the host namespace of the vcluster and the objects migrated are limited
+// to the ones synced from the virtual namespace. The migration status is synced up with
+// the migrated host objects mapped to their virtual objects.
+func NewMigrationSyncer(ctx *synccontext.RegisterContext) syncer.Base {
+	return &migrationSyncer{
+		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, "migration", &storkv1alpha1.Migration{}),
+		physicalReader:       ctx.PhysicalManager.GetAPIReader(),
+	}
+}
+
+type migrationSyncer struct {
+	translator.NamespacedTranslator
+
+	physicalReader client.Reader
+}
+
+var _ syncer.Initializer = &migrationSyncer{}
+
+func (s *migrationSyncer) Init(ctx *synccontext.RegisterContext) error {
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		storkv1alpha1.SchemeGroupVersion.WithKind("Migration"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD Migration from physical cluster")
+	}
+
+	return nil
+}
+
+// IsManaged ignores the host migrations created by host MigrationSchedules, which have
+// no virtual counterpart and must not be deleted as orphans.
+func (s *migrationSyncer) IsManaged(pObj client.Object) (bool, error) {
+	for _, owner := range pObj.GetOwnerReferences() {
+		if owner.Kind == "MigrationSchedule" {
+			return false, nil
+		}
+	}
+
+	return s.NamespacedTranslator.IsManaged(pObj)
+}
+
+func (s *migrationSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vMigration := vObj.(*storkv1alpha1.Migration)
+	spec, err := translateMigrationSpec(vMigration.Spec, vMigration.Namespace, ctx.TargetNamespace)
+	if err != nil {
+		s.EventRecorder().Eventf(vMigration, corev1.EventTypeWarning, "SyncError", "Migration is not synced: %v", err)
+		return ctrl.Result{}, nil
+	}
+
+	pMigration := translateMetadata(s, vMigration).(*storkv1alpha1.Migration)
+	pMigration.Spec = *spec
+	pMigration.Status = storkv1alpha1.MigrationStatus{}
+
+	return s.SyncDownCreate(ctx, vObj, pMigration)
+}
+
+func (s *migrationSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(pObj) || isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vMigration := vObj.(*storkv1alpha1.Migration)
+	pMigration := pObj.(*storkv1alpha1.Migration)
+
+	status := s.translateStatus(ctx, pMigration.Status, vMigration.Namespace)
+	if !equality.Semantic.DeepEqual(vMigration.Status, status) {
+		updated := vMigration.DeepCopy()
+		updated.Status = status
+		ctx.Log.Infof("update virtual migration status %s/%s", vMigration.Namespace, vMigration.Name)
+		if err := ctx.VirtualClient.Update(ctx.Context, updated); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "update virtual migration status")
+		}
+	}
+
+	spec, err := translateMigrationSpec(vMigration.Spec, vMigration.Namespace, ctx.TargetNamespace)
+	if err != nil {
+		s.EventRecorder().Eventf(vMigration, corev1.EventTypeWarning, "SyncError", "Migration is not synced: %v", err)
+		return ctrl.Result{}, nil
+	}
+
+	var updated *storkv1alpha1.Migration
+	changed, updatedAnnotations, updatedLabels := translateMetadataUpdate(s, vMigration, pMigration)
+	if changed {
+		updated = pMigration.DeepCopy()
+		updated.Annotations = updatedAnnotations
+		updated.Labels = updatedLabels
+	}
+	if !equality.Semantic.DeepEqual(pMigration.Spec, *spec) {
+		if updated == nil {
+			updated = pMigration.DeepCopy()
+		}
+		updated.Spec = *spec
+	}
+
+	return s.SyncDownUpdate(ctx, vObj, updated)
+}
+
+// translateStatus returns the status of a host migration for the virtual migration in
+// the given namespace. Migrated resources and volumes are reported with the names of
+// their virtual objects, entries of objects outside the virtual namespace are dropped.
+func (s *migrationSyncer) translateStatus(
+	ctx *synccontext.SyncContext,
+	pStatus storkv1alpha1.MigrationStatus,
+	vNamespace string,
+) storkv1alpha1.MigrationStatus {
+	status := *pStatus.DeepCopy()
+	status.Resources = []*storkv1alpha1.MigrationResourceInfo{}
+	for _, resource := range pStatus.Resources {
+		gvk := schema.GroupVersionKind{Group: resource.Group, Version: resource.Version, Kind: resource.Kind}
+		name, ok := s.virtualName(ctx, gvk, resource.Namespace, resource.Name, vNamespace)
+		if !ok {
+			continue
+		}
+
+		translated := resource.DeepCopy()
+		translated.Namespace, translated.Name = vNamespace, name
+		status.Resources = append(status.Resources, translated)
+	}
+
+	status.Volumes = []*storkv1alpha1.MigrationVolumeInfo{}
+	for _, volume := range pStatus.Volumes {
+		gvk := corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim")
+		name, ok := s.virtualName(ctx, gvk, volume.Namespace, volume.PersistentVolumeClaim, vNamespace)
+		if !ok {
+			continue
+		}
+
+		translated := volume.DeepCopy()
+		translated.Namespace, translated.PersistentVolumeClaim = vNamespace, name
+		status.Volumes = append(status.Volumes, translated)
+	}
+
+	if len(status.Resources) == 0 {
+		status.Resources = nil
+	}
+	if len(status.Volumes) == 0 {
+		status.Volumes = nil
+	}
+	return status
+}
+
+// virtualName returns the name of the virtual object in vNamespace the host object was
+// synced from, or false if it has none.
+func (s *migrationSyncer) virtualName(
+	ctx *synccontext.SyncContext,
+	gvk schema.GroupVersionKind,
+	pNamespace string,
+	pName string,
+	vNamespace string,
+) (string, bool) {
+	if pNamespace != ctx.TargetNamespace {
+		return "", false
+	}
+
+	pObj := &unstructured.Unstructured{}
+	pObj.SetGroupVersionKind(gvk)
+	if err := s.physicalReader.Get(ctx.Context, client.ObjectKey{Namespace: pNamespace, Name: pName}, pObj); err != nil {
+		if !kerrors.IsNotFound(err) {
+			ctx.Log.Infof("error getting migrated %s %s/%s: %v", gvk.Kind, pNamespace, pName, err)
+		}
+		return "", false
+	}
+
+	pAnnotations := pObj.GetAnnotations()
+	if pAnnotations[translator.NamespaceAnnotation] != vNamespace || pAnnotations[translator.NameAnnotation] == "" {
+		return "", false
+	}
+	return pAnnotations[translator.NameAnnotation], true
+}
+
+// NewMigrationScheduleSyncer returns a syncer for Stork MigrationSchedules, whose
+// migration template is translated like Migrations. Schedule policies are cluster scoped
+// and provided by the admin, so they are referenced as is.
+func NewMigrationScheduleSyncer(ctx *synccontext.RegisterContext) syncer.Base {
+	return &migrationScheduleSyncer{
+		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, "migrationschedule", &storkv1alpha1.MigrationSchedule{}),
+	}
+}
+
+type migrationScheduleSyncer struct {
+	translator.NamespacedTranslator
+}
+
+var _ syncer.Initializer = &migrationScheduleSyncer{}
+
+func (s *migrationScheduleSyncer) Init(ctx *synccontext.RegisterContext) error {
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		storkv1alpha1.SchemeGroupVersion.WithKind("MigrationSchedule"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD MigrationSchedule from physical cluster")
+	}
+
+	return nil
+}
+
+func (s *migrationScheduleSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vSchedule := vObj.(*storkv1alpha1.MigrationSchedule)
+	spec, err := translateMigrationScheduleSpec(vSchedule, ctx.TargetNamespace)
+	if err != nil {
+		s.EventRecorder().Eventf(vSchedule, corev1.EventTypeWarning, "SyncError", "Migration schedule is not synced: %v", err)
+		return ctrl.Result{}, nil
+	}
+
+	pSchedule := translateMetadata(s, vSchedule).(*storkv1alpha1.MigrationSchedule)
+	pSchedule.Spec = *spec
+	pSchedule.Status = storkv1alpha1.MigrationScheduleStatus{}
+
+	return s.SyncDownCreate(ctx, vObj, pSchedule)
+}
+
+func (s *migrationScheduleSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(pObj) || isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vSchedule := vObj.(*storkv1alpha1.MigrationSchedule)
+	pSchedule := pObj.(*storkv1alpha1.MigrationSchedule)
+
+	status := translateMigrationScheduleStatus(pSchedule, vSchedule)
+	if !equality.Semantic.DeepEqual(vSchedule.Status, status) {
+		updated := vSchedule.DeepCopy()
+		updated.Status = status
+		ctx.Log.Infof("update virtual migration schedule status %s/%s", vSchedule.Namespace, vSchedule.Name)
+		if err := ctx.VirtualClient.Update(ctx.Context, updated); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "update virtual migration schedule status")
+		}
+	}
+
+	spec, err := translateMigrationScheduleSpec(vSchedule, ctx.TargetNamespace)
+	if err != nil {
+		s.EventRecorder().Eventf(vSchedule, corev1.EventTypeWarning, "SyncError", "Migration schedule is not synced: %v", err)
+		return ctrl.Result{}, nil
+	}
+
+	var updated *storkv1alpha1.MigrationSchedule
+	changed, updatedAnnotations, updatedLabels := translateMetadataUpdate(s, vSchedule, pSchedule)
+	if changed {
+		updated = pSchedule.DeepCopy()
+		updated.Annotations = updatedAnnotations
+		updated.Labels = updatedLabels
+	}
+	if !equality.Semantic.DeepEqual(pSchedule.Spec, *spec) {
+		if updated == nil {
+			updated = pSchedule.DeepCopy()
+		}
+		updated.Spec = *spec
+	}
+
+	return s.SyncDownUpdate(ctx, vObj, updated)
+}
+
+// translateMigrationScheduleStatus returns the status of a host schedule for its virtual
+// schedule. Stork names the migrations of a schedule after the schedule, which are
+// reported with the name of the virtual schedule. Other migrations are dropped.
+func translateMigrationScheduleStatus(
+	pSchedule *storkv1alpha1.MigrationSchedule,
+	vSchedule *storkv1alpha1.MigrationSchedule,
+) storkv1alpha1.MigrationScheduleStatus {
+	status := storkv1alpha1.MigrationScheduleStatus{}
+	for policyType, migrations := range pSchedule.Status.Items {
+		translated := []*storkv1alpha1.ScheduledMigrationStatus{}
+		for _, migration := range migrations {
+			suffix := strings.TrimPrefix(migration.Name, pSchedule.Name+"-")
+			if suffix == migration.Name {
+				continue
+			}
+
+			m := migration.DeepCopy()
+			m.Name = vSchedule.Name + "-" + suffix
+			translated = append(translated, m)
+		}
+		if len(translated) == 0 {
+			continue
+		}
+
+		if status.Items == nil {
+			status.Items = map[string][]*storkv1alpha1.ScheduledMigrationStatus{}
+		}
+		status.Items[policyType] = translated
+	}
+
+	return status
+}
+
+func translateMigrationScheduleSpec(
+	vSchedule *storkv1alpha1.MigrationSchedule,
+	targetNamespace string,
+) (*storkv1alpha1.MigrationScheduleSpec, error) {
+	template, err := translateMigrationSpec(vSchedule.Spec.Template.Spec, vSchedule.Namespace, targetNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := vSchedule.Spec.DeepCopy()
+	spec.Template.Spec = *template
+	return spec, nil
+}
+
+// translateMigrationSpec rewrites the spec of a migration in the virtual namespace. All
+// virtual namespaces share the host namespace of the vcluster, as multi-namespace mode is
+// refused, so a migration covers its own virtual namespace only, whose objects are
+// selected through the vcluster labels. Cluster pairs and rules are looked up by Stork in
+// the namespace of the migration.
+func translateMigrationSpec(
+	vSpec storkv1alpha1.MigrationSpec,
+	vNamespace string,
+	targetNamespace string,
+) (*storkv1alpha1.MigrationSpec, error) {
+	if len(vSpec.Namespaces) != 1 {
+		return nil, errors.Errorf("exactly one namespace can be migrated, got %d", len(vSpec.Namespaces))
+	}
+	// tenants may only migrate the namespace they create the migration in
+	if vSpec.Namespaces[0] != vNamespace {
+		return nil, errors.Errorf("only namespace %s of the migration can be migrated, got %s", vNamespace, vSpec.Namespaces[0])
+	}
+	if vSpec.ClusterPair == "" {
+		return nil, errors.New("cluster pair is missing")
+	}
+
+	pSpec := vSpec.DeepCopy()
+	pSpec.Namespaces = []string{targetNamespace}
+	pSpec.ClusterPair = translate.PhysicalName(vSpec.ClusterPair, vNamespace)
+	if vSpec.PreExecRule != "" {
+		pSpec.PreExecRule = translate.PhysicalName(vSpec.PreExecRule, vNamespace)
+	}
+	if vSpec.PostExecRule != "" {
+		pSpec.PostExecRule = translate.PhysicalName(vSpec.PostExecRule, vNamespace)
+	}
+
+	pSpec.Selectors = map[string]string{
+		translate.MarkerLabel:    translate.Suffix,
+		translate.NamespaceLabel: vSpec.Namespaces[0],
+	}
+	for k, v := range vSpec.Selectors {
+		pSpec.Selectors[translator.ConvertLabelKey(k)] = v
+	}
+
+	return pSpec, nil
+}
@@ -0,0 +1,77 @@
+package syncers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storkv1alpha1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/stork/v1alpha1"
+)
+
+func TestTranslateMigrationSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     storkv1alpha1.MigrationSpec
+		expected *storkv1alpha1.MigrationSpec
+	}{
+		{
+			name: "own namespace",
+			spec: storkv1alpha1.MigrationSpec{
+				ClusterPair: "dr",
+				Namespaces:  []string{"app"},
+				Selectors:   map[string]string{"tier": "db"},
+			},
+			expected: &storkv1alpha1.MigrationSpec{
+				ClusterPair: translate.PhysicalName("dr", "app"),
+				Namespaces:  []string{"vcluster"},
+				Selectors: map[string]string{
+					translate.MarkerLabel:              translate.Suffix,
+					translate.NamespaceLabel:           "app",
+					translator.ConvertLabelKey("tier"): "db",
+				},
+			},
+		},
+		{
+			name: "other namespace",
+			spec: storkv1alpha1.MigrationSpec{ClusterPair: "dr", Namespaces: []string{"other"}},
+		},
+		{
+			name: "multiple namespaces",
+			spec: storkv1alpha1.MigrationSpec{ClusterPair: "dr", Namespaces: []string{"app", "other"}},
+		},
+		{
+			name: "missing cluster pair",
+			spec: storkv1alpha1.MigrationSpec{Namespaces: []string{"app"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := translateMigrationSpec(tt.spec, "app", "vcluster")
+			if (err != nil) != (tt.expected == nil) {
+				t.Fatalf("expected error %v, got %v", tt.expected == nil, err)
+			}
+			if diff := cmp.Diff(tt.expected, spec); diff != "" {
+				t.Errorf("unexpected spec (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTranslateMigrationScheduleSpec(t *testing.T) {
+	vSchedule := &storkv1alpha1.MigrationSchedule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "nightly"},
+		Spec: storkv1alpha1.MigrationScheduleSpec{
+			Template: storkv1alpha1.MigrationTemplateSpec{
+				Spec: storkv1alpha1.MigrationSpec{ClusterPair: "dr", Namespaces: []string{"other"}},
+			},
+		},
+	}
+
+	if _, err := translateMigrationScheduleSpec(vSchedule, "vcluster"); err == nil {
+		t.Errorf("expected schedule migrating another namespace to be rejected")
+	}
+}
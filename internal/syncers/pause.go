@@ -0,0 +1,14 @@
+package syncers
+
+import "sigs.k8s.io/controller-runtime/pkg/client"
+
+// PausedAnnotation halts the sync of a single object while set to "true". It is honoured
+// on virtual objects as well as on host objects, so admins can pause an object without
+// access to the vcluster. Deleting a paused virtual object still deletes its host object.
+// Imported objects are owned by the host and are only paused through the host object.
+const PausedAnnotation = "pxe.portworx.io/paused"
+
+// isPaused returns true if the sync of the object is paused.
+func isPaused(obj client.Object) bool {
+	return obj.GetAnnotations()[PausedAnnotation] == "true"
+}
@@ -0,0 +1,124 @@
+package syncers
+
+import (
+	"context"
+
+	"github.com/loft-sh/vcluster-sdk/hook"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/portworx/pxe-vcluster/internal/policy"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+// NewPVCHook returns a hook which mutates the PersistentVolumeClaims vcluster syncs to
+// the host cluster. Portworx copies the labels of a PVC to the volume it provisions, so
+// the provenance labels set here also end up on the Portworx volume. References to
+// encryption Secrets are rewritten to the translated host Secrets and, if tokenSecret is
+// set, the PVC is provisioned with the Portworx token of the vcluster. PVCs violating
+// pvcPolicy, if set, are not created on the host and an event is recorded on the virtual
+// PVC. Updates are always synced, offending values keep the value of the host PVC.
+func NewPVCHook(ctx *synccontext.RegisterContext, tokenSecret string, pvcPolicy *policy.PVCPolicy) hook.ClientHook {
+	return &pvcHook{
+		tokenSecret:     tokenSecret,
+		policy:          pvcPolicy,
+		eventRecorder:   ctx.VirtualManager.GetEventRecorderFor("px-pvc-hook"),
+		targetNamespace: ctx.TargetNamespace,
+		virtualClient:   ctx.VirtualManager.GetClient(),
+		physicalClient:  ctx.PhysicalManager.GetClient(),
+	}
+}
+
+type pvcHook struct {
+	tokenSecret     string
+	policy          *policy.PVCPolicy
+	eventRecorder   record.EventRecorder
+	targetNamespace string
+	virtualClient   client.Client
+	physicalClient  client.Client
+}
+
+func (h *pvcHook) Name() string {
+	return "px-pvc-hook"
+}
+
+func (h *pvcHook) Resource() client.Object {
+	return &corev1.PersistentVolumeClaim{}
+}
+
+var _ hook.MutateCreatePhysical = &pvcHook{}
+
+func (h *pvcHook) MutateCreatePhysical(ctx context.Context, obj client.Object) (client.Object, error) {
+	return h.mutatePhysical(ctx, obj, false)
+}
+
+var _ hook.MutateUpdatePhysical = &pvcHook{}
+
+func (h *pvcHook) MutateUpdatePhysical(ctx context.Context, obj client.Object) (client.Object, error) {
+	return h.mutatePhysical(ctx, obj, true)
+}
+
+func (h *pvcHook) mutatePhysical(ctx context.Context, obj client.Object, update bool) (client.Object, error) {
+	pPVC, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return nil, errors.Errorf("object %v is not a persistent volume claim", obj)
+	}
+
+	owner, ok := provenance.FromObject(pPVC)
+	if !ok {
+		return pPVC, nil
+	}
+
+	vPVC := &corev1.PersistentVolumeClaim{}
+	if err := h.virtualClient.Get(ctx, client.ObjectKey{
+		Namespace: pPVC.Annotations[translator.NamespaceAnnotation],
+		Name:      pPVC.Annotations[translator.NameAnnotation],
+	}, vPVC); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return nil, errors.Wrap(err, "get virtual persistent volume claim")
+		}
+	} else {
+		owner = provenance.OwnerOf(vPVC)
+	}
+
+	if h.policy != nil {
+		// on updates the policy leaves the values already on the host as they are
+		var current *corev1.PersistentVolumeClaim
+		if update {
+			current = &corev1.PersistentVolumeClaim{}
+			if err := h.physicalClient.Get(ctx, client.ObjectKeyFromObject(pPVC), current); err != nil {
+				return nil, errors.Wrap(err, "get physical persistent volume claim")
+			}
+		}
+		if err := h.policy.Apply(pPVC, current); err != nil {
+			if vPVC.Name != "" {
+				h.eventRecorder.Eventf(vPVC, corev1.EventTypeWarning, "PolicyViolation", "Rejected by storage policy: %v", err)
+			}
+			return nil, errors.Wrapf(err, "persistent volume claim %s/%s violates storage policy", owner.Namespace, owner.Name)
+		}
+	}
+
+	owner.VCluster = translate.Suffix
+	provenance.Stamp(pPVC, owner)
+
+	if err := translateSecretAnnotations(
+		ctx,
+		h.virtualClient,
+		h.physicalClient,
+		h.targetNamespace,
+		owner.Namespace,
+		pPVC.Annotations,
+	); err != nil {
+		return nil, errors.Wrapf(err, "persistent volume claim %s/%s", owner.Namespace, owner.Name)
+	}
+	injectTokenSecret(pPVC.Annotations, h.tokenSecret, h.targetNamespace)
+	stripVolumeStatus(pPVC.Annotations)
+
+	return pPVC, nil
+}
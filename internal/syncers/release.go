@@ -0,0 +1,60 @@
+package syncers
+
+import (
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RetainAnnotation requests the plugin to release the host object of a virtual
+	// object, so deleting the virtual object doesn't delete the host object (and with it
+	// the Portworx snapshot). It is only honored once the release was approved on the host.
+	RetainAnnotation = "pxe.portworx.io/retain-host-object"
+	// ReleasedAnnotation is set on the virtual object once its host object was released.
+	ReleasedAnnotation = "pxe.portworx.io/host-object-released"
+	// ReleaseApprovedAnnotation is set on the host object by migrate-snapshots and holds
+	// the UID of the virtual object whose release was approved. Tenants can't write host
+	// objects, so they can't keep host and Portworx snapshots on their own.
+	ReleaseApprovedAnnotation = "pxe.portworx.io/release-approved"
+)
+
+// shouldRelease returns true if the virtual object requested to release its host object
+// and the release was approved on the host object.
+func shouldRelease(pObj, vObj client.Object) bool {
+	return vObj.GetAnnotations()[RetainAnnotation] == "true" &&
+		vObj.GetUID() != "" &&
+		pObj.GetAnnotations()[ReleaseApprovedAnnotation] == string(vObj.GetUID())
+}
+
+// releasePhysical removes the vcluster marker from the host object, which keeps vcluster
+// from deleting it together with the virtual object, and acknowledges the release on
+// the virtual object.
+func releasePhysical(ctx *synccontext.SyncContext, pObj, vObj client.Object) (ctrl.Result, error) {
+	if translate.IsManaged(pObj) {
+		ctx.Log.Infof("release physical %s/%s, because virtual object requested to retain it", pObj.GetNamespace(), pObj.GetName())
+		patch := client.MergeFrom(pObj.DeepCopyObject().(client.Object))
+		pLabels := pObj.GetLabels()
+		delete(pLabels, translate.MarkerLabel)
+		pObj.SetLabels(pLabels)
+		if err := ctx.PhysicalClient.Patch(ctx.Context, pObj, patch); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "release physical object")
+		}
+	}
+
+	if vObj.GetAnnotations()[ReleasedAnnotation] == "true" {
+		return ctrl.Result{}, nil
+	}
+
+	patch := client.MergeFrom(vObj.DeepCopyObject().(client.Object))
+	vAnnotations := vObj.GetAnnotations()
+	vAnnotations[ReleasedAnnotation] = "true"
+	vObj.SetAnnotations(vAnnotations)
+	if err := ctx.VirtualClient.Patch(ctx.Context, vObj, patch); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "acknowledge release on virtual object")
+	}
+
+	return ctrl.Result{}, nil
+}
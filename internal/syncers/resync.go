@@ -0,0 +1,194 @@
+package syncers
+
+import (
+	"context"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/portworx/pxe-vcluster/internal/hostguard"
+)
+
+// NewResyncer wraps a syncer, so all its virtual objects are reconciled again when the host
+// guard switches to active mode. Writes skipped while the plugin was paused or in dry-run
+// mode are performed then instead of with the next change of each object. Controllers
+// which aren't syncers are returned unchanged, they watch the switch on their own.
+func NewResyncer(s syncer.Base, modes *hostguard.Switch) syncer.Base {
+	realSyncer, ok := s.(syncer.Syncer)
+	if !ok {
+		return s
+	}
+
+	return &resyncer{
+		Syncer: realSyncer,
+		modes:  modes,
+	}
+}
+
+type resyncer struct {
+	syncer.Syncer
+	modes *hostguard.Switch
+}
+
+var _ syncer.Initializer = &resyncer{}
+
+func (r *resyncer) Init(ctx *synccontext.RegisterContext) error {
+	if initializer, ok := r.Syncer.(syncer.Initializer); ok {
+		return initializer.Init(ctx)
+	}
+	return nil
+}
+
+var _ syncer.IndicesRegisterer = &resyncer{}
+
+func (r *resyncer) RegisterIndices(ctx *synccontext.RegisterContext) error {
+	if indicesRegisterer, ok := r.Syncer.(syncer.IndicesRegisterer); ok {
+		return indicesRegisterer.RegisterIndices(ctx)
+	}
+	return nil
+}
+
+var _ syncer.Starter = &resyncer{}
+
+func (r *resyncer) ReconcileStart(ctx *synccontext.SyncContext, req ctrl.Request) (bool, error) {
+	if starter, ok := r.Syncer.(syncer.Starter); ok {
+		return starter.ReconcileStart(ctx, req)
+	}
+	return false, nil
+}
+
+func (r *resyncer) ReconcileEnd() {
+	if starter, ok := r.Syncer.(syncer.Starter); ok {
+		starter.ReconcileEnd()
+	}
+}
+
+var _ syncer.UpSyncer = &resyncer{}
+
+// SyncUp deletes host objects without a virtual object like the vcluster-sdk does for
+// syncers which don't implement syncer.UpSyncer.
+func (r *resyncer) SyncUp(ctx *synccontext.SyncContext, pObj client.Object) (ctrl.Result, error) {
+	if upSyncer, ok := r.Syncer.(syncer.UpSyncer); ok {
+		return upSyncer.SyncUp(ctx, pObj)
+	}
+
+	managed, err := r.Syncer.IsManaged(pObj)
+	if err != nil {
+		return ctrl.Result{}, err
+	} else if !managed {
+		return ctrl.Result{}, nil
+	}
+	return syncer.DeleteObject(ctx, pObj)
+}
+
+var _ syncer.ControllerModifier = &resyncer{}
+
+func (r *resyncer) ModifyController(ctx *synccontext.RegisterContext, builder *builder.Builder) (*builder.Builder, error) {
+	if modifier, ok := r.Syncer.(syncer.ControllerModifier); ok {
+		var err error
+		builder, err = modifier.ModifyController(ctx, builder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return watchActivation(ctx, builder, r.modes, r.Syncer.Resource(), r.Name())
+}
+
+// watchActivation adds a watch to the controller of the given virtual kind, which
+// enqueues all its virtual objects whenever the host guard switches to active mode.
+func watchActivation(
+	ctx *synccontext.RegisterContext,
+	builder *builder.Builder,
+	modes *hostguard.Switch,
+	obj client.Object,
+	name string,
+) (*builder.Builder, error) {
+	virtualClient := ctx.VirtualManager.GetClient()
+	list, err := newList(virtualClient.Scheme(), obj)
+	if err != nil {
+		return nil, err
+	}
+
+	activated := modes.Activated()
+	events := make(chan event.GenericEvent)
+	go func() {
+		for {
+			select {
+			case <-ctx.Context.Done():
+				return
+			case <-activated:
+			}
+
+			select {
+			case <-ctx.Context.Done():
+				return
+			case events <- event.GenericEvent{Object: obj}:
+			}
+		}
+	}()
+
+	return builder.Watches(
+		&source.Channel{Source: events},
+		handler.EnqueueRequestsFromMapFunc(allObjects(virtualClient, list, log.New(name))),
+	), nil
+}
+
+// allObjects returns a mapper which enqueues every object of the kind of the list.
+func allObjects(c client.Client, list client.ObjectList, logger log.Logger) handler.MapFunc {
+	return func(client.Object) []reconcile.Request {
+		objs := list.DeepCopyObject().(client.ObjectList)
+		if err := c.List(context.Background(), objs); err != nil {
+			logger.Errorf("error listing objects to resync: %v", err)
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+		err := meta.EachListItem(objs, func(obj runtime.Object) error {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{
+				Namespace: accessor.GetNamespace(),
+				Name:      accessor.GetName(),
+			}})
+			return nil
+		})
+		if err != nil {
+			logger.Errorf("error listing objects to resync: %v", err)
+			return nil
+		}
+		return requests
+	}
+}
+
+// newList returns an empty list of the kind of obj.
+func newList(scheme *runtime.Scheme, obj client.Object) (client.ObjectList, error) {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kind of object")
+	}
+
+	list, err := scheme.New(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "create list of %s", gvk.Kind)
+	}
+	objList, ok := list.(client.ObjectList)
+	if !ok {
+		return nil, errors.Errorf("%sList is not a list", gvk.Kind)
+	}
+	return objList, nil
+}
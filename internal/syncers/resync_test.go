@@ -0,0 +1,40 @@
+package syncers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+)
+
+func TestResyncAllObjects(t *testing.T) {
+	virtualClient := fake.NewClientBuilder().WithScheme(plugin.Scheme).WithObjects(
+		&snapshotv1.VolumeSnapshot{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "daily"}},
+		&snapshotv1.VolumeSnapshot{ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "nightly"}},
+	).Build()
+
+	list, err := newList(plugin.Scheme, &snapshotv1.VolumeSnapshot{})
+	if err != nil {
+		t.Fatalf("new list: %v", err)
+	}
+	expected := []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "app", Name: "daily"}},
+		{NamespacedName: client.ObjectKey{Namespace: "db", Name: "nightly"}},
+	}
+	requests := allObjects(virtualClient, list, log.New("test"))(&snapshotv1.VolumeSnapshot{})
+	if diff := cmp.Diff(expected, requests); diff != "" {
+		t.Errorf("unexpected requests (-expected +actual):\n%s", diff)
+	}
+
+	if _, err := newList(runtime.NewScheme(), &snapshotv1.VolumeSnapshot{}); err == nil {
+		t.Errorf("expected error for a kind missing in the scheme")
+	}
+}
@@ -0,0 +1,85 @@
+package syncers
+
+import (
+	"context"
+
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Annotations Portworx uses to find the Secret holding the passphrase of an encrypted
+// volume or snapshot.
+const (
+	pxSecretNameAnnotation      = "px/secret-name"
+	pxSecretNamespaceAnnotation = "px/secret-namespace"
+	pxSecretKeyAnnotation       = "px/secret-key"
+)
+
+// translateSecretAnnotations rewrites the Portworx secret annotations of a host object,
+// which reference a Secret of the virtual cluster, to the translated host Secret. Only
+// Secrets which exist in the virtual cluster may be referenced, which keeps tenants from
+// pointing Portworx to arbitrary Secrets of the host cluster.
+func translateSecretAnnotations(
+	ctx context.Context,
+	virtualClient client.Client,
+	physicalClient client.Client,
+	targetNamespace string,
+	vNamespace string,
+	annotations map[string]string,
+) error {
+	secret, err := validateSecretAnnotations(ctx, virtualClient, vNamespace, annotations)
+	if err != nil || secret == nil {
+		return err
+	}
+
+	pName := translate.PhysicalName(secret.Name, secret.Namespace)
+	if err := physicalClient.Get(ctx, client.ObjectKey{Namespace: targetNamespace, Name: pName}, &corev1.Secret{}); err != nil {
+		if kerrors.IsNotFound(err) {
+			return errors.Errorf("secret %s/%s is not synced to the host cluster, make sure vcluster syncs all secrets", secret.Namespace, secret.Name)
+		}
+		return errors.Wrap(err, "get physical secret")
+	}
+
+	annotations[pxSecretNameAnnotation] = pName
+	annotations[pxSecretNamespaceAnnotation] = targetNamespace
+	return nil
+}
+
+// validateSecretAnnotations checks that the Secret referenced by the Portworx secret
+// annotations of a virtual object exists in the virtual cluster and returns its key, or
+// nil if no Secret is referenced.
+func validateSecretAnnotations(
+	ctx context.Context,
+	virtualReader client.Reader,
+	vNamespace string,
+	annotations map[string]string,
+) (*client.ObjectKey, error) {
+	name := annotations[pxSecretNameAnnotation]
+	if name == "" {
+		return nil, nil
+	}
+
+	namespace := annotations[pxSecretNamespaceAnnotation]
+	if namespace == "" {
+		namespace = vNamespace
+	}
+
+	vSecret := &corev1.Secret{}
+	if err := virtualReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, vSecret); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, errors.Errorf("secret %s/%s referenced by %s does not exist in the virtual cluster", namespace, name, pxSecretNameAnnotation)
+		}
+		return nil, errors.Wrap(err, "get virtual secret")
+	}
+
+	if key := annotations[pxSecretKeyAnnotation]; key != "" {
+		if _, ok := vSecret.Data[key]; !ok {
+			return nil, errors.Errorf("secret %s/%s has no key %s", namespace, name, key)
+		}
+	}
+
+	return &client.ObjectKey{Namespace: namespace, Name: name}, nil
+}
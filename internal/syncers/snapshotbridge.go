@@ -0,0 +1,590 @@
+package syncers
+
+import (
+	"context"
+	"time"
+
+	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/convert"
+	"github.com/portworx/pxe-vcluster/internal/hostguard"
+	"github.com/portworx/pxe-vcluster/internal/policy"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+// bridgedSnapshotLabel is set on the virtual VolumeSnapshotData the csi bridge creates for
+// a virtual snapshot and holds the name of the host csi snapshot.
+const bridgedSnapshotLabel = "pxe.portworx.io/bridged-snapshot"
+
+// bridgedByLabel is set on the host snapshots a bridge creates and holds the name of the
+// bridge. Bridges only watch and delete host snapshots carrying their own name, as host
+// snapshots of the same kind are also created by the volumesnapshot syncer or by vcluster.
+const bridgedByLabel = "pxe.portworx.io/bridged-by"
+
+// NewCSISnapshotBridge returns a controller which fulfills virtual legacy external-storage
+// VolumeSnapshots with CSI VolumeSnapshots in the host cluster and maps the CSI status back
+// to legacy conditions. Ready snapshots are bound to a virtual VolumeSnapshotData holding
+// the Portworx snapshot of the host, which Stork restores them from. It replaces the
+// volumesnapshot syncer when enabled.
+func NewCSISnapshotBridge(
+	ctx *synccontext.RegisterContext,
+	snapshotClassName string,
+	metadataPolicy *policy.MetadataPolicy,
+	modes *hostguard.Switch,
+) syncer.Base {
+	return &csiSnapshotBridge{
+		snapshotClassName: snapshotClassName,
+		metadataPolicy:    metadataPolicy,
+		modes:             modes,
+		log:               log.New("volumesnapshot-csi-bridge"),
+	}
+}
+
+type csiSnapshotBridge struct {
+	snapshotClassName string
+	metadataPolicy    *policy.MetadataPolicy
+	modes             *hostguard.Switch
+	log               log.Logger
+
+	eventRecorder   record.EventRecorder
+	targetNamespace string
+	virtualClient   client.Client
+	physicalClient  client.Client
+	physicalReader  client.Reader
+}
+
+func (b *csiSnapshotBridge) Name() string {
+	return "volumesnapshot-csi-bridge"
+}
+
+var _ syncer.Initializer = &csiSnapshotBridge{}
+
+func (b *csiSnapshotBridge) Init(ctx *synccontext.RegisterContext) error {
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		snapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshot"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD VolumeSnapshot from physical cluster")
+	}
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		snapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshotData"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD VolumeSnapshotData from physical cluster")
+	}
+
+	return nil
+}
+
+var _ syncer.ControllerStarter = &csiSnapshotBridge{}
+
+func (b *csiSnapshotBridge) Register(ctx *synccontext.RegisterContext) error {
+	b.eventRecorder = ctx.VirtualManager.GetEventRecorderFor(b.Name())
+	b.targetNamespace = ctx.TargetNamespace
+	b.virtualClient = ctx.VirtualManager.GetClient()
+	b.physicalClient = ctx.PhysicalManager.GetClient()
+	// volume snapshot contents are cluster scoped and not in the namespaced cache
+	b.physicalReader = ctx.PhysicalManager.GetAPIReader()
+
+	controller := ctrl.NewControllerManagedBy(ctx.VirtualManager).
+		Named(b.Name()).
+		For(&snapshotv1.VolumeSnapshot{}).
+		Watches(
+			source.NewKindWithCache(&csisnapshotv1.VolumeSnapshot{}, ctx.PhysicalManager.GetCache()),
+			handler.EnqueueRequestsFromMapFunc(bridgedToVirtualRequest(b.Name())),
+		)
+	controller, err := watchActivation(ctx, controller, b.modes, &snapshotv1.VolumeSnapshot{}, b.Name())
+	if err != nil {
+		return err
+	}
+	return controller.Complete(b)
+}
+
+func (b *csiSnapshotBridge) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	vSnapshot := &snapshotv1.VolumeSnapshot{}
+	if err := b.virtualClient.Get(ctx, req.NamespacedName, vSnapshot); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		vSnapshot = nil
+	}
+
+	pSnapshot := &csisnapshotv1.VolumeSnapshot{}
+	if err := b.physicalClient.Get(ctx, physicalName(b.targetNamespace, req.NamespacedName), pSnapshot); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		pSnapshot = nil
+	}
+	if vSnapshot != nil && (isPaused(vSnapshot) || pSnapshot != nil && isPaused(pSnapshot)) {
+		return ctrl.Result{}, nil
+	}
+
+	switch {
+	case vSnapshot == nil && pSnapshot != nil:
+		if !isBridgedBy(pSnapshot, b.Name()) {
+			return ctrl.Result{}, nil
+		}
+		if err := b.deleteSnapshotData(ctx, pSnapshot.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, deleteBridged(ctx, b.log, b.physicalClient, b.Name(), pSnapshot)
+	case vSnapshot != nil && pSnapshot == nil:
+		if isImported(vSnapshot) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, b.syncDown(ctx, vSnapshot)
+	case vSnapshot != nil && pSnapshot != nil:
+		if !isBridgedBy(pSnapshot, b.Name()) {
+			return ctrl.Result{}, errors.Errorf("host snapshot %s/%s was not created by %s", pSnapshot.Namespace, pSnapshot.Name, b.Name())
+		}
+		return ctrl.Result{}, b.syncUp(ctx, pSnapshot, vSnapshot)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (b *csiSnapshotBridge) syncDown(ctx context.Context, vSnapshot *snapshotv1.VolumeSnapshot) error {
+	if vSnapshot.Spec.PersistentVolumeClaimName == "" {
+		b.log.Infof("skip volume snapshot %s/%s, because it has no persistent volume claim", vSnapshot.Namespace, vSnapshot.Name)
+		return nil
+	}
+
+	pMeta, err := physicalSnapshotMetadata(
+		ctx,
+		b.virtualClient,
+		b.physicalClient,
+		b.metadataPolicy,
+		b.targetNamespace,
+		vSnapshot,
+	)
+	if err != nil {
+		b.eventRecorder.Eventf(vSnapshot, corev1.EventTypeWarning, "SyncError", "Invalid snapshot annotations: %v", err)
+		return err
+	}
+
+	pMeta.Labels[bridgedByLabel] = b.Name()
+
+	pvcName := translate.PhysicalName(vSnapshot.Spec.PersistentVolumeClaimName, vSnapshot.Namespace)
+	pSnapshot := &csisnapshotv1.VolumeSnapshot{
+		ObjectMeta: pMeta,
+		Spec: csisnapshotv1.VolumeSnapshotSpec{
+			Source: csisnapshotv1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: &pvcName,
+			},
+		},
+	}
+	if b.snapshotClassName != "" {
+		pSnapshot.Spec.VolumeSnapshotClassName = &b.snapshotClassName
+	}
+
+	b.log.Infof("create physical csi volume snapshot %s/%s", pSnapshot.Namespace, pSnapshot.Name)
+	if err := b.physicalClient.Create(ctx, pSnapshot); err != nil {
+		return errors.Wrap(err, "create physical csi volume snapshot")
+	}
+
+	return nil
+}
+
+func (b *csiSnapshotBridge) syncUp(ctx context.Context, pSnapshot *csisnapshotv1.VolumeSnapshot, vSnapshot *snapshotv1.VolumeSnapshot) error {
+	updated := vSnapshot.DeepCopy()
+	updated.Status = convert.LegacySnapshotStatus(pSnapshot.Status)
+	if updated.Spec.SnapshotDataName == "" && convert.IsLegacySnapshotReady(updated.Status) {
+		dataName, err := b.bindSnapshotData(ctx, pSnapshot, vSnapshot)
+		if err != nil {
+			return err
+		}
+		updated.Spec.SnapshotDataName = dataName
+	}
+	if equality.Semantic.DeepEqual(updated, vSnapshot) {
+		return nil
+	}
+
+	if err := b.virtualClient.Update(ctx, updated); err != nil {
+		return errors.Wrap(err, "update virtual volume snapshot")
+	}
+
+	return nil
+}
+
+// bindSnapshotData creates the virtual snapshot data of a ready virtual snapshot, which
+// points at the Portworx snapshot taken for the host csi snapshot. It returns the name of
+// the snapshot data or an empty name, if the host snapshot has no snapshot handle yet.
+func (b *csiSnapshotBridge) bindSnapshotData(
+	ctx context.Context,
+	pSnapshot *csisnapshotv1.VolumeSnapshot,
+	vSnapshot *snapshotv1.VolumeSnapshot,
+) (string, error) {
+	if pSnapshot.Status == nil || pSnapshot.Status.BoundVolumeSnapshotContentName == nil {
+		return "", nil
+	}
+
+	content := &csisnapshotv1.VolumeSnapshotContent{}
+	if err := b.physicalReader.Get(ctx, client.ObjectKey{Name: *pSnapshot.Status.BoundVolumeSnapshotContentName}, content); err != nil {
+		return "", errors.Wrap(client.IgnoreNotFound(err), "get physical volume snapshot content")
+	}
+	if content.Status == nil || content.Status.SnapshotHandle == nil {
+		return "", nil
+	}
+
+	vData := &snapshotv1.VolumeSnapshotData{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   vSnapshot.Namespace,
+			Name:        "k8s-volume-snapshot-" + string(vSnapshot.UID),
+			Labels:      map[string]string{bridgedSnapshotLabel: pSnapshot.Name},
+			Annotations: map[string]string{ImportedFromAnnotation: content.Name},
+		},
+		Spec: snapshotv1.VolumeSnapshotDataSpec{
+			VolumeSnapshotDataSource: snapshotv1.VolumeSnapshotDataSource{
+				PortworxSnapshot: &snapshotv1.PortworxVolumeSnapshotSource{
+					SnapshotID:        *content.Status.SnapshotHandle,
+					SnapshotType:      snapshotv1.PortworxSnapshotTypeLocal,
+					VolumeProvisioner: content.Spec.Driver,
+				},
+			},
+			VolumeSnapshotRef: &corev1.ObjectReference{
+				Kind:      "VolumeSnapshot",
+				Namespace: vSnapshot.Namespace,
+				Name:      vSnapshot.Name,
+				UID:       vSnapshot.UID,
+			},
+		},
+		Status: snapshotv1.VolumeSnapshotDataStatus{
+			Conditions: []snapshotv1.VolumeSnapshotDataCondition{{
+				Type:               snapshotv1.VolumeSnapshotDataConditionReady,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+				Message:            "Snapshot data of the host csi volume snapshot",
+			}},
+		},
+	}
+	if content.Status.CreationTime != nil {
+		vData.Status.CreationTimestamp = metav1.NewTime(time.Unix(0, *content.Status.CreationTime))
+	}
+
+	vPVC := &corev1.PersistentVolumeClaim{}
+	if err := b.virtualClient.Get(ctx, client.ObjectKey{Namespace: vSnapshot.Namespace, Name: vSnapshot.Spec.PersistentVolumeClaimName}, vPVC); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return "", errors.Wrap(err, "get virtual persistent volume claim")
+		}
+	} else if vPVC.Spec.VolumeName != "" {
+		vData.Spec.PersistentVolumeRef = &corev1.ObjectReference{Kind: "PersistentVolume", Name: vPVC.Spec.VolumeName}
+	}
+
+	b.log.Infof("create virtual volume snapshot data %s for volume snapshot %s/%s", vData.Name, vSnapshot.Namespace, vSnapshot.Name)
+	if err := b.virtualClient.Create(ctx, vData); err != nil {
+		if !kerrors.IsAlreadyExists(err) {
+			return "", errors.Wrap(err, "create virtual volume snapshot data")
+		}
+
+		// a previous reconcile may have created the snapshot data and failed to bind it
+		existing := &snapshotv1.VolumeSnapshotData{}
+		if err := b.virtualClient.Get(ctx, client.ObjectKeyFromObject(vData), existing); err != nil {
+			return "", errors.Wrap(err, "get existing virtual volume snapshot data")
+		}
+		if existing.Labels[bridgedSnapshotLabel] != pSnapshot.Name {
+			return "", errors.Errorf("virtual volume snapshot data %s already exists", vData.Name)
+		}
+	}
+
+	return vData.Name, nil
+}
+
+// deleteSnapshotData deletes the virtual snapshot data created for the host csi snapshot.
+func (b *csiSnapshotBridge) deleteSnapshotData(ctx context.Context, pSnapshotName string) error {
+	vDatas := &snapshotv1.VolumeSnapshotDataList{}
+	if err := b.virtualClient.List(ctx, vDatas, client.MatchingLabels{bridgedSnapshotLabel: pSnapshotName}); err != nil {
+		return errors.Wrap(err, "list virtual volume snapshot data")
+	}
+
+	for i := range vDatas.Items {
+		b.log.Infof("delete virtual volume snapshot data %s, because virtual volume snapshot was deleted", vDatas.Items[i].Name)
+		if err := b.virtualClient.Delete(ctx, &vDatas.Items[i]); err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrap(err, "delete virtual volume snapshot data")
+		}
+	}
+
+	return nil
+}
+
+// NewLegacySnapshotBridge returns a controller which fulfills virtual CSI VolumeSnapshots
+// with legacy external-storage VolumeSnapshots in the host cluster and maps the legacy
+// conditions back to the CSI status. vcluster's own volume snapshot sync must be disabled
+// when this controller is enabled, snapshots already taken by it are not taken again.
+func NewLegacySnapshotBridge(
+	ctx *synccontext.RegisterContext,
+	metadataPolicy *policy.MetadataPolicy,
+	modes *hostguard.Switch,
+) syncer.Base {
+	return &legacySnapshotBridge{
+		metadataPolicy: metadataPolicy,
+		modes:          modes,
+		log:            log.New("csi-volumesnapshot-legacy-bridge"),
+	}
+}
+
+type legacySnapshotBridge struct {
+	metadataPolicy *policy.MetadataPolicy
+	modes          *hostguard.Switch
+	log            log.Logger
+
+	eventRecorder   record.EventRecorder
+	targetNamespace string
+	virtualClient   client.Client
+	physicalClient  client.Client
+}
+
+func (b *legacySnapshotBridge) Name() string {
+	return "csi-volumesnapshot-legacy-bridge"
+}
+
+var _ syncer.Initializer = &legacySnapshotBridge{}
+
+func (b *legacySnapshotBridge) Init(ctx *synccontext.RegisterContext) error {
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		csisnapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshot"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD csi VolumeSnapshot from physical cluster")
+	}
+
+	return nil
+}
+
+var _ syncer.ControllerStarter = &legacySnapshotBridge{}
+
+func (b *legacySnapshotBridge) Register(ctx *synccontext.RegisterContext) error {
+	b.eventRecorder = ctx.VirtualManager.GetEventRecorderFor(b.Name())
+	b.targetNamespace = ctx.TargetNamespace
+	b.virtualClient = ctx.VirtualManager.GetClient()
+	b.physicalClient = ctx.PhysicalManager.GetClient()
+
+	controller := ctrl.NewControllerManagedBy(ctx.VirtualManager).
+		Named(b.Name()).
+		For(&csisnapshotv1.VolumeSnapshot{}).
+		Watches(
+			source.NewKindWithCache(&snapshotv1.VolumeSnapshot{}, ctx.PhysicalManager.GetCache()),
+			handler.EnqueueRequestsFromMapFunc(bridgedToVirtualRequest(b.Name())),
+		)
+	controller, err := watchActivation(ctx, controller, b.modes, &csisnapshotv1.VolumeSnapshot{}, b.Name())
+	if err != nil {
+		return err
+	}
+	return controller.Complete(b)
+}
+
+func (b *legacySnapshotBridge) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	vSnapshot := &csisnapshotv1.VolumeSnapshot{}
+	if err := b.virtualClient.Get(ctx, req.NamespacedName, vSnapshot); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		vSnapshot = nil
+	}
+
+	pSnapshot := &snapshotv1.VolumeSnapshot{}
+	if err := b.physicalClient.Get(ctx, physicalName(b.targetNamespace, req.NamespacedName), pSnapshot); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		pSnapshot = nil
+	}
+	if vSnapshot != nil && (isPaused(vSnapshot) || pSnapshot != nil && isPaused(pSnapshot)) {
+		return ctrl.Result{}, nil
+	}
+
+	switch {
+	case vSnapshot == nil && pSnapshot != nil:
+		return ctrl.Result{}, deleteBridged(ctx, b.log, b.physicalClient, b.Name(), pSnapshot)
+	case vSnapshot != nil && pSnapshot == nil:
+		return ctrl.Result{}, b.syncDown(ctx, vSnapshot)
+	case vSnapshot != nil && pSnapshot != nil:
+		if !isBridgedBy(pSnapshot, b.Name()) {
+			return ctrl.Result{}, errors.Errorf("host snapshot %s/%s was not created by %s", pSnapshot.Namespace, pSnapshot.Name, b.Name())
+		}
+		return ctrl.Result{}, b.syncUp(ctx, pSnapshot, vSnapshot)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (b *legacySnapshotBridge) syncDown(ctx context.Context, vSnapshot *csisnapshotv1.VolumeSnapshot) error {
+	if vSnapshot.Spec.Source.PersistentVolumeClaimName == nil {
+		b.log.Infof("skip csi volume snapshot %s/%s, because pre-provisioned snapshots are not supported", vSnapshot.Namespace, vSnapshot.Name)
+		return nil
+	}
+
+	pMeta, err := physicalSnapshotMetadata(
+		ctx,
+		b.virtualClient,
+		b.physicalClient,
+		b.metadataPolicy,
+		b.targetNamespace,
+		vSnapshot,
+	)
+	if err != nil {
+		b.eventRecorder.Eventf(vSnapshot, corev1.EventTypeWarning, "SyncError", "Invalid snapshot annotations: %v", err)
+		return err
+	}
+
+	pMeta.Labels[bridgedByLabel] = b.Name()
+
+	pSnapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: pMeta,
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			PersistentVolumeClaimName: translate.PhysicalName(
+				*vSnapshot.Spec.Source.PersistentVolumeClaimName,
+				vSnapshot.Namespace,
+			),
+		},
+	}
+
+	// vcluster's own volume snapshot sync creates a host csi snapshot of the same name
+	pCSISnapshot := &csisnapshotv1.VolumeSnapshot{}
+	if err := b.physicalClient.Get(ctx, client.ObjectKeyFromObject(pSnapshot), pCSISnapshot); err == nil {
+		return errors.Errorf(
+			"host csi volume snapshot %s/%s exists, disable sync.volumesnapshots of vcluster to use %s",
+			pSnapshot.Namespace,
+			pSnapshot.Name,
+			b.Name(),
+		)
+	} else if !kerrors.IsNotFound(err) {
+		return errors.Wrap(err, "get physical csi volume snapshot")
+	}
+
+	b.log.Infof("create physical volume snapshot %s/%s", pSnapshot.Namespace, pSnapshot.Name)
+	if err := b.physicalClient.Create(ctx, pSnapshot); err != nil {
+		return errors.Wrap(err, "create physical volume snapshot")
+	}
+
+	return nil
+}
+
+func (b *legacySnapshotBridge) syncUp(ctx context.Context, pSnapshot *snapshotv1.VolumeSnapshot, vSnapshot *csisnapshotv1.VolumeSnapshot) error {
+	status := convert.CSISnapshotStatus(pSnapshot.Status)
+	if equality.Semantic.DeepEqual(status, vSnapshot.Status) {
+		return nil
+	}
+
+	vSnapshot.Status = status
+	if err := b.virtualClient.Status().Update(ctx, vSnapshot); err != nil {
+		return errors.Wrap(err, "update virtual csi volume snapshot status")
+	}
+
+	return nil
+}
+
+// physicalMetadata returns the translated metadata of a host object, which is fulfilling
+// the virtual object with an object of a different kind.
+func physicalMetadata(targetNamespace string, vObj client.Object) metav1.ObjectMeta {
+	translated := translator.TranslateMetadata(targetNamespace, vObj)
+	pMeta := metav1.ObjectMeta{
+		Namespace:       translated.GetNamespace(),
+		Name:            translated.GetName(),
+		Labels:          translated.GetLabels(),
+		Annotations:     translated.GetAnnotations(),
+		OwnerReferences: translated.GetOwnerReferences(),
+	}
+	provenance.Stamp(&pMeta, provenance.OwnerOf(vObj))
+	return pMeta
+}
+
+// physicalSnapshotMetadata returns the host metadata of a bridged snapshot. Like the
+// volumesnapshot syncer, it drops the labels and annotations the metadata policy doesn't
+// sync down, validates the Stork and Portworx annotations and translates the secret and
+// rule annotations.
+func physicalSnapshotMetadata(
+	ctx context.Context,
+	virtualClient client.Client,
+	physicalClient client.Client,
+	metadataPolicy *policy.MetadataPolicy,
+	targetNamespace string,
+	vObj client.Object,
+) (metav1.ObjectMeta, error) {
+	pMeta := physicalMetadata(targetNamespace, filterMetadata(metadataPolicy.Rules("VolumeSnapshot").Down, vObj))
+	vNamespace := vObj.GetNamespace()
+	if err := translateSecretAnnotations(ctx, virtualClient, physicalClient, targetNamespace, vNamespace, pMeta.Annotations); err != nil {
+		return metav1.ObjectMeta{}, err
+	}
+	if err := validateStorkAnnotations(ctx, virtualClient, metadataPolicy, targetNamespace, vNamespace, pMeta.Annotations); err != nil {
+		return metav1.ObjectMeta{}, err
+	}
+	translateSnapshotRuleAnnotations(pMeta.Annotations, vNamespace)
+
+	return pMeta, nil
+}
+
+func physicalName(targetNamespace string, req types.NamespacedName) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: targetNamespace,
+		Name:      translate.PhysicalName(req.Name, req.Namespace),
+	}
+}
+
+// bridgedToVirtualRequest maps events of the host objects created by the given bridge to
+// their virtual object.
+func bridgedToVirtualRequest(bridgeName string) handler.MapFunc {
+	return func(pObj client.Object) []reconcile.Request {
+		if !isBridgedBy(pObj, bridgeName) {
+			return nil
+		}
+		return physicalToVirtualRequest(pObj)
+	}
+}
+
+// physicalToVirtualRequest maps events of managed host objects to their virtual object.
+func physicalToVirtualRequest(pObj client.Object) []reconcile.Request {
+	if !translate.IsManaged(pObj) {
+		return nil
+	}
+
+	pAnnotations := pObj.GetAnnotations()
+	if pAnnotations[translator.NameAnnotation] == "" {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: pAnnotations[translator.NamespaceAnnotation],
+		Name:      pAnnotations[translator.NameAnnotation],
+	}}}
+}
+
+// isBridgedBy returns true if the host object is managed by the vcluster and was created
+// by the given bridge.
+func isBridgedBy(pObj client.Object, bridgeName string) bool {
+	return translate.IsManaged(pObj) && pObj.GetLabels()[bridgedByLabel] == bridgeName
+}
+
+// deleteBridged deletes the host object, if it was created by the given bridge.
+func deleteBridged(ctx context.Context, log log.Logger, physicalClient client.Client, bridgeName string, pObj client.Object) error {
+	if !isBridgedBy(pObj, bridgeName) {
+		return nil
+	}
+
+	log.Infof("delete physical %s/%s, because virtual object was deleted", pObj.GetNamespace(), pObj.GetName())
+	return client.IgnoreNotFound(physicalClient.Delete(ctx, pObj))
+}
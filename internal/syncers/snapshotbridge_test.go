@@ -0,0 +1,73 @@
+package syncers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+)
+
+func hostSnapshot(name string, labels map[string]string) *snapshotv1.VolumeSnapshot {
+	return &snapshotv1.VolumeSnapshot{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "vcluster",
+		Name:        name,
+		Labels:      labels,
+		Annotations: map[string]string{translator.NameAnnotation: "snap", translator.NamespaceAnnotation: "ns"},
+	}}
+}
+
+func TestBridgedHostSnapshots(t *testing.T) {
+	const bridgeName = "csi-volumesnapshot-legacy-bridge"
+
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		bridged bool
+	}{
+		{
+			name:    "created by the bridge",
+			labels:  map[string]string{translate.MarkerLabel: translate.Suffix, bridgedByLabel: bridgeName},
+			bridged: true,
+		},
+		{
+			name:   "created by the volumesnapshot syncer",
+			labels: map[string]string{translate.MarkerLabel: translate.Suffix},
+		},
+		{
+			name:   "created by another bridge",
+			labels: map[string]string{translate.MarkerLabel: translate.Suffix, bridgedByLabel: "volumesnapshot-csi-bridge"},
+		},
+		{
+			name:   "not managed by the vcluster",
+			labels: map[string]string{bridgedByLabel: bridgeName},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pSnapshot := hostSnapshot("snap-x-ns-x-suffix", tt.labels)
+			physicalClient := fake.NewClientBuilder().WithScheme(plugin.Scheme).WithObjects(pSnapshot).Build()
+
+			requests := bridgedToVirtualRequest(bridgeName)(pSnapshot)
+			if mapped := len(requests) == 1; mapped != tt.bridged {
+				t.Errorf("expected mapped %v, got requests %v", tt.bridged, requests)
+			}
+
+			if err := deleteBridged(context.Background(), log.New("test"), physicalClient, bridgeName, pSnapshot); err != nil {
+				t.Fatalf("delete bridged: %v", err)
+			}
+			err := physicalClient.Get(context.Background(), client.ObjectKeyFromObject(pSnapshot), &snapshotv1.VolumeSnapshot{})
+			if deleted := err != nil; deleted != tt.bridged {
+				t.Errorf("expected deleted %v, got %v", tt.bridged, err)
+			}
+		})
+	}
+}
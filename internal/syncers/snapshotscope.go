@@ -0,0 +1,178 @@
+package syncers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+)
+
+// SkipSyncAnnotation opts a virtual snapshot or snapshot data out of being synced to the
+// host cluster.
+const SkipSyncAnnotation = "pxe.portworx.io/skip-sync"
+
+// Ignored conditions tell tenants why their snapshot is not synced to the host cluster.
+const (
+	snapshotConditionIgnored     snapshotv1.VolumeSnapshotConditionType     = "Ignored"
+	snapshotDataConditionIgnored snapshotv1.VolumeSnapshotDataConditionType = "Ignored"
+
+	reasonSyncOptOut           = "SyncOptOut"
+	reasonNamespaceNotSelected = "NamespaceNotSelected"
+)
+
+// snapshotScope selects the virtual namespaces whose snapshots are synced. Objects which
+// are not selected are left alone, including host objects which were synced before.
+type snapshotScope struct {
+	// namespaceSelector selects the virtual namespaces by their labels, nil selects all
+	namespaceSelector labels.Selector
+}
+
+// ignoreReason returns the reason and a message for tenants if the virtual object in the
+// given namespace is not synced, or an empty reason if it is synced.
+func (s snapshotScope) ignoreReason(ctx context.Context, virtualClient client.Client, vObj client.Object, namespace string) (string, string, error) {
+	if vObj.GetAnnotations()[SkipSyncAnnotation] == "true" {
+		return reasonSyncOptOut, fmt.Sprintf("syncing is disabled through the %s annotation", SkipSyncAnnotation), nil
+	}
+	if s.namespaceSelector == nil || s.namespaceSelector.Empty() || namespace == "" {
+		return "", "", nil
+	}
+
+	vNamespace := &corev1.Namespace{}
+	if err := virtualClient.Get(ctx, client.ObjectKey{Name: namespace}, vNamespace); err != nil {
+		return "", "", errors.Wrapf(err, "get virtual namespace %s", namespace)
+	}
+	if !s.namespaceSelector.Matches(labels.Set(vNamespace.Labels)) {
+		return reasonNamespaceNotSelected, fmt.Sprintf(
+			"snapshots are only synced in namespaces matching %q, ask your administrator to enable namespace %s",
+			s.namespaceSelector.String(),
+			namespace,
+		), nil
+	}
+
+	return "", "", nil
+}
+
+// snapshotsInNamespace enqueues all virtual snapshots of a namespace, whose labels might
+// have changed the selection.
+func snapshotsInNamespace(virtualClient client.Client) func(client.Object) []reconcile.Request {
+	return func(obj client.Object) []reconcile.Request {
+		vSnapshots := &snapshotv1.VolumeSnapshotList{}
+		if err := virtualClient.List(context.Background(), vSnapshots, client.InNamespace(obj.GetName())); err != nil {
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+		for _, vSnapshot := range vSnapshots.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&vSnapshot)})
+		}
+		return requests
+	}
+}
+
+// ignored returns true if the virtual snapshot is not synced and keeps the ignored
+// condition of the snapshot up to date.
+func (s *snapshotSyncer) ignored(ctx *synccontext.SyncContext, vSnapshot *snapshotv1.VolumeSnapshot) (bool, error) {
+	reason, message, err := s.scope.ignoreReason(ctx.Context, ctx.VirtualClient, vSnapshot, vSnapshot.Namespace)
+	if err != nil {
+		return false, err
+	}
+
+	condition := snapshotCondition(vSnapshot, snapshotConditionIgnored)
+	if reason == "" {
+		if condition == nil {
+			return false, nil
+		}
+
+		updated := vSnapshot.DeepCopy()
+		updated.Status.Conditions = withoutSnapshotCondition(updated.Status.Conditions, snapshotConditionIgnored)
+		return false, errors.Wrap(ctx.VirtualClient.Update(ctx.Context, updated), "update virtual volume snapshot status")
+	}
+	if condition != nil && condition.Reason == reason && condition.Message == message {
+		return true, nil
+	}
+
+	ctx.Log.Infof("ignore virtual volume snapshot %s/%s: %s", vSnapshot.Namespace, vSnapshot.Name, reason)
+	updated := vSnapshot.DeepCopy()
+	updated.Status.Conditions = append(
+		withoutSnapshotCondition(updated.Status.Conditions, snapshotConditionIgnored),
+		snapshotv1.VolumeSnapshotCondition{
+			Type:               snapshotConditionIgnored,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             reason,
+			Message:            message,
+		},
+	)
+	return true, errors.Wrap(ctx.VirtualClient.Update(ctx.Context, updated), "update virtual volume snapshot status")
+}
+
+// ignored returns true if the virtual snapshot data is not synced and keeps the ignored
+// condition of the data up to date. Snapshot data is selected through the namespace of
+// the snapshot it is bound to.
+func (s *snapshotDataSyncer) ignored(ctx *synccontext.SyncContext, vData *snapshotv1.VolumeSnapshotData) (bool, error) {
+	namespace := vData.Namespace
+	if ref := snapshotDataRef(vData); namespace == "" && strings.Contains(ref, "/") {
+		namespace = strings.SplitN(ref, "/", 2)[0]
+	}
+	reason, message, err := s.scope.ignoreReason(ctx.Context, ctx.VirtualClient, vData, namespace)
+	if err != nil {
+		return false, err
+	}
+
+	conditions := []snapshotv1.VolumeSnapshotDataCondition{}
+	var condition *snapshotv1.VolumeSnapshotDataCondition
+	for i := range vData.Status.Conditions {
+		if vData.Status.Conditions[i].Type == snapshotDataConditionIgnored {
+			condition = &vData.Status.Conditions[i]
+			continue
+		}
+		conditions = append(conditions, vData.Status.Conditions[i])
+	}
+
+	if reason == "" {
+		if condition == nil {
+			return false, nil
+		}
+
+		updated := vData.DeepCopy()
+		updated.Status.Conditions = conditions
+		return false, errors.Wrap(ctx.VirtualClient.Update(ctx.Context, updated), "update virtual volume snapshot data status")
+	}
+	if condition != nil && condition.Reason == reason && condition.Message == message {
+		return true, nil
+	}
+
+	ctx.Log.Infof("ignore virtual volume snapshot data %s: %s", vData.Name, reason)
+	updated := vData.DeepCopy()
+	updated.Status.Conditions = append(conditions, snapshotv1.VolumeSnapshotDataCondition{
+		Type:               snapshotDataConditionIgnored,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+	return true, errors.Wrap(ctx.VirtualClient.Update(ctx.Context, updated), "update virtual volume snapshot data status")
+}
+
+// snapshotDataRef returns the namespace/name of the snapshot the data is bound to.
+func snapshotDataRef(vData *snapshotv1.VolumeSnapshotData) string {
+	if vData.Spec.VolumeSnapshotRef == nil {
+		return ""
+	}
+
+	// the snapshot controller references snapshots as namespace/name
+	namespace, name := vData.Spec.VolumeSnapshotRef.Namespace, vData.Spec.VolumeSnapshotRef.Name
+	if strings.Contains(name, "/") || namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
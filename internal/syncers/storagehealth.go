@@ -0,0 +1,245 @@
+package syncers
+
+import (
+	"context"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pxcorev1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/core/v1"
+)
+
+func init() {
+	_ = pxcorev1.AddToScheme(plugin.Scheme)
+}
+
+// StorageHealthNamespace is the virtual namespace the read-only copies of
+// StorageClusters and StorageNodes are created in.
+const StorageHealthNamespace = "kube-system"
+
+const storageHealthSyncerName = "px-storage-health"
+
+// NewStorageHealthSyncer returns a controller which imports read-only copies of the host
+// StorageClusters and of the StorageNodes of nodes synced into the vcluster. Copies only
+// hold phase, version, capacity and conditions. They are never synced down, changes
+// made by tenants are overwritten with the next sync.
+func NewStorageHealthSyncer(ctx *synccontext.RegisterContext, interval time.Duration) syncer.Base {
+	return &storageHealthSyncer{
+		interval: interval,
+		log:      log.New(storageHealthSyncerName),
+	}
+}
+
+type storageHealthSyncer struct {
+	interval time.Duration
+	log      log.Logger
+
+	// StorageClusters live outside the vcluster namespace, which the physical cache is
+	// restricted to, so the host is read without cache
+	physicalReader client.Reader
+	virtualClient  client.Client
+}
+
+func (s *storageHealthSyncer) Name() string {
+	return storageHealthSyncerName
+}
+
+var _ syncer.Initializer = &storageHealthSyncer{}
+
+func (s *storageHealthSyncer) Init(ctx *synccontext.RegisterContext) error {
+	for _, kind := range []string{"StorageCluster", "StorageNode"} {
+		if err := translate.EnsureCRDFromPhysicalCluster(
+			ctx.Context,
+			ctx.PhysicalManager.GetConfig(),
+			ctx.VirtualManager.GetConfig(),
+			pxcorev1.SchemeGroupVersion.WithKind(kind),
+		); err != nil {
+			return errors.Wrapf(err, "ensure CRD %s from physical cluster", kind)
+		}
+	}
+
+	return nil
+}
+
+var _ syncer.ControllerStarter = &storageHealthSyncer{}
+
+func (s *storageHealthSyncer) Register(ctx *synccontext.RegisterContext) error {
+	s.physicalReader = ctx.PhysicalManager.GetAPIReader()
+	s.virtualClient = ctx.VirtualManager.GetClient()
+
+	go wait.UntilWithContext(ctx.Context, func(ctx context.Context) {
+		if err := s.syncClusters(ctx); err != nil {
+			s.log.Errorf("error syncing storage clusters: %v", err)
+		}
+		if err := s.syncNodes(ctx); err != nil {
+			s.log.Errorf("error syncing storage nodes: %v", err)
+		}
+	}, s.interval)
+
+	return nil
+}
+
+func (s *storageHealthSyncer) syncClusters(ctx context.Context) error {
+	pClusters := &pxcorev1.StorageClusterList{}
+	if err := s.physicalReader.List(ctx, pClusters); err != nil {
+		return errors.Wrap(err, "list physical storage clusters")
+	}
+	vClusters := &pxcorev1.StorageClusterList{}
+	if err := s.virtualClient.List(ctx, vClusters, client.InNamespace(StorageHealthNamespace)); err != nil {
+		return errors.Wrap(err, "list virtual storage clusters")
+	}
+
+	existing := map[string]*pxcorev1.StorageCluster{}
+	for i := range vClusters.Items {
+		existing[vClusters.Items[i].Name] = &vClusters.Items[i]
+	}
+
+	for i := range pClusters.Items {
+		pCluster := &pClusters.Items[i]
+		desired := &pxcorev1.StorageCluster{
+			ObjectMeta: importedStorageMeta(pCluster),
+			Status:     pCluster.Status,
+		}
+
+		var vObj client.Object
+		equal := false
+		if vCluster, ok := existing[pCluster.Name]; ok {
+			delete(existing, pCluster.Name)
+			vObj = vCluster
+			equal = equality.Semantic.DeepEqual(vCluster.Status, desired.Status) &&
+				equality.Semantic.DeepEqual(vCluster.Annotations, desired.Annotations)
+		}
+		if err := s.mirror(ctx, vObj, desired, equal); err != nil {
+			return errors.Wrapf(err, "mirror storage cluster %s", pCluster.Name)
+		}
+	}
+
+	for _, vCluster := range existing {
+		if err := s.deleteImported(ctx, vCluster); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncNodes mirrors the StorageNodes of the nodes synced into the vcluster, so tenants
+// only learn about nodes they can already see.
+func (s *storageHealthSyncer) syncNodes(ctx context.Context) error {
+	vNodes := &corev1.NodeList{}
+	if err := s.virtualClient.List(ctx, vNodes); err != nil {
+		return errors.Wrap(err, "list virtual nodes")
+	}
+	synced := map[string]bool{}
+	for _, vNode := range vNodes.Items {
+		synced[vNode.Name] = true
+	}
+
+	pNodes := &pxcorev1.StorageNodeList{}
+	if err := s.physicalReader.List(ctx, pNodes); err != nil {
+		return errors.Wrap(err, "list physical storage nodes")
+	}
+	vStorageNodes := &pxcorev1.StorageNodeList{}
+	if err := s.virtualClient.List(ctx, vStorageNodes, client.InNamespace(StorageHealthNamespace)); err != nil {
+		return errors.Wrap(err, "list virtual storage nodes")
+	}
+
+	existing := map[string]*pxcorev1.StorageNode{}
+	for i := range vStorageNodes.Items {
+		existing[vStorageNodes.Items[i].Name] = &vStorageNodes.Items[i]
+	}
+
+	for i := range pNodes.Items {
+		pNode := &pNodes.Items[i]
+		if !synced[pNode.Name] {
+			continue
+		}
+
+		desired := &pxcorev1.StorageNode{
+			ObjectMeta: importedStorageMeta(pNode),
+			Spec:       pNode.Spec,
+			Status:     pNode.Status,
+		}
+
+		var vObj client.Object
+		equal := false
+		if vNode, ok := existing[pNode.Name]; ok {
+			delete(existing, pNode.Name)
+			vObj = vNode
+			equal = equality.Semantic.DeepEqual(vNode.Spec, desired.Spec) &&
+				equality.Semantic.DeepEqual(vNode.Status, desired.Status) &&
+				equality.Semantic.DeepEqual(vNode.Annotations, desired.Annotations)
+		}
+		if err := s.mirror(ctx, vObj, desired, equal); err != nil {
+			return errors.Wrapf(err, "mirror storage node %s", pNode.Name)
+		}
+	}
+
+	for _, vNode := range existing {
+		if err := s.deleteImported(ctx, vNode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mirror creates or updates the virtual copy vObj, nil if missing, to match desired.
+func (s *storageHealthSyncer) mirror(ctx context.Context, vObj, desired client.Object, equal bool) error {
+	if equal {
+		return nil
+	}
+
+	// the status subresource ignores the status on create and update and create
+	// overwrites desired with the response, so keep a copy for the status update
+	status := desired.DeepCopyObject().(client.Object)
+	if vObj == nil {
+		if err := s.virtualClient.Create(ctx, desired); err != nil {
+			return errors.Wrap(err, "create")
+		}
+	} else {
+		desired.SetResourceVersion(vObj.GetResourceVersion())
+		if err := s.virtualClient.Update(ctx, desired); err != nil {
+			return errors.Wrap(err, "update")
+		}
+	}
+
+	status.SetResourceVersion(desired.GetResourceVersion())
+	return errors.Wrap(s.virtualClient.Status().Update(ctx, status), "update status")
+}
+
+func (s *storageHealthSyncer) deleteImported(ctx context.Context, vObj client.Object) error {
+	if !isImported(vObj) {
+		return nil
+	}
+
+	s.log.Infof("delete virtual %s, because the host object is gone or not visible", vObj.GetName())
+	if err := s.virtualClient.Delete(ctx, vObj); err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrapf(err, "delete virtual %s", vObj.GetName())
+	}
+
+	return nil
+}
+
+// importedStorageMeta returns the metadata of the virtual copy of a host object. Labels
+// and annotations of the host object are not copied.
+func importedStorageMeta(pObj client.Object) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: StorageHealthNamespace,
+		Name:      pObj.GetName(),
+		Annotations: map[string]string{
+			ImportedFromAnnotation: pObj.GetNamespace() + "/" + pObj.GetName(),
+		},
+	}
+}
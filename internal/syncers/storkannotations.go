@@ -0,0 +1,82 @@
+package syncers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storkv1alpha1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/stork/v1alpha1"
+	"github.com/portworx/pxe-vcluster/internal/policy"
+)
+
+// Annotations of VolumeSnapshots which change how Stork and Portworx take and restore
+// snapshots on the host. Together with the pre and post snapshot rule annotations they
+// are only synced after validation.
+const (
+	pxSnapshotTypeAnnotation         = "portworx/snapshot-type"
+	pxCloudCredAnnotation            = "portworx/cloud-cred-id"
+	storkRestoreNamespacesAnnotation = "stork/snapshot-restore-namespaces"
+
+	snapshotTypeLocal = "local"
+	snapshotTypeCloud = "cloud"
+)
+
+// validateStorkAnnotations checks the Stork and Portworx annotations of a snapshot in the
+// given virtual namespace against the metadata policy. Restore namespaces are rewritten
+// to the target namespace, the only namespace a snapshot can be restored in.
+func validateStorkAnnotations(
+	ctx context.Context,
+	virtualReader client.Reader,
+	metadataPolicy *policy.MetadataPolicy,
+	targetNamespace string,
+	vNamespace string,
+	annotations map[string]string,
+) error {
+	snapshotType := annotations[pxSnapshotTypeAnnotation]
+	switch snapshotType {
+	case "", snapshotTypeLocal:
+	case snapshotTypeCloud:
+		if !metadataPolicy.CloudSnapshotsAllowed() {
+			return errors.New("cloud snapshots are not allowed")
+		}
+	default:
+		return errors.Errorf("invalid %s %q", pxSnapshotTypeAnnotation, snapshotType)
+	}
+
+	if credID := annotations[pxCloudCredAnnotation]; credID != "" {
+		if snapshotType != snapshotTypeCloud {
+			return errors.Errorf("%s is only supported for cloud snapshots", pxCloudCredAnnotation)
+		}
+		if !metadataPolicy.CloudCredentialAllowed(credID) {
+			return errors.Errorf("cloud credentials %q are not allowed", credID)
+		}
+	}
+
+	for _, key := range []string{storkv1alpha1.PreSnapshotRuleAnnotation, storkv1alpha1.PostSnapshotRuleAnnotation} {
+		name := annotations[key]
+		if name == "" {
+			continue
+		}
+
+		if err := virtualReader.Get(ctx, client.ObjectKey{Namespace: vNamespace, Name: name}, &storkv1alpha1.Rule{}); err != nil {
+			if kerrors.IsNotFound(err) {
+				return errors.Errorf("%s references rule %s/%s, which doesn't exist", key, vNamespace, name)
+			}
+			return errors.Wrapf(err, "get rule %s/%s", vNamespace, name)
+		}
+	}
+
+	if value, ok := annotations[storkRestoreNamespacesAnnotation]; ok {
+		for _, namespace := range strings.Split(value, ",") {
+			if namespace = strings.TrimSpace(namespace); namespace != "" && namespace != vNamespace {
+				return errors.Errorf("snapshots can only be restored in namespace %s", vNamespace)
+			}
+		}
+		annotations[storkRestoreNamespacesAnnotation] = targetNamespace
+	}
+
+	return nil
+}
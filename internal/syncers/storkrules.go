@@ -0,0 +1,112 @@
+package syncers
+
+import (
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storkv1alpha1 "github.com/portworx/pxe-vcluster/apis/libopenstorage/stork/v1alpha1"
+)
+
+func init() {
+	_ = storkv1alpha1.AddToScheme(plugin.Scheme)
+}
+
+// NewStorkRuleSyncer returns a syncer for Stork Rules, which run pre and post snapshot
+// actions in pods. Pod selectors are rewritten to only match the host pods of the
+// virtual namespace of the rule.
+func NewStorkRuleSyncer(ctx *synccontext.RegisterContext) syncer.Base {
+	return &storkRuleSyncer{
+		NamespacedTranslator: translator.NewNamespacedTranslator(ctx, "storkrule", &storkv1alpha1.Rule{}),
+	}
+}
+
+type storkRuleSyncer struct {
+	translator.NamespacedTranslator
+}
+
+var _ syncer.Initializer = &storkRuleSyncer{}
+
+func (s *storkRuleSyncer) Init(ctx *synccontext.RegisterContext) error {
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		storkv1alpha1.SchemeGroupVersion.WithKind("Rule"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD Rule from physical cluster")
+	}
+
+	return nil
+}
+
+func (s *storkRuleSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vRule := vObj.(*storkv1alpha1.Rule)
+	pRule := translateMetadata(s, vRule).(*storkv1alpha1.Rule)
+	pRule.Rules = translateRuleItems(vRule.Rules, vRule.Namespace)
+
+	return s.SyncDownCreate(ctx, vObj, pRule)
+}
+
+func (s *storkRuleSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(pObj) || isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	vRule := vObj.(*storkv1alpha1.Rule)
+	pRule := pObj.(*storkv1alpha1.Rule)
+
+	var updated *storkv1alpha1.Rule
+	changed, updatedAnnotations, updatedLabels := translateMetadataUpdate(s, vRule, pRule)
+	if changed {
+		updated = pRule.DeepCopy()
+		updated.Annotations = updatedAnnotations
+		updated.Labels = updatedLabels
+	}
+
+	rules := translateRuleItems(vRule.Rules, vRule.Namespace)
+	if !equality.Semantic.DeepEqual(pRule.Rules, rules) {
+		if updated == nil {
+			updated = pRule.DeepCopy()
+		}
+		updated.Rules = rules
+	}
+
+	return s.SyncDownUpdate(ctx, vObj, updated)
+}
+
+// translateRuleItems rewrites the pod selectors of the rule items to the labels of the
+// host pods synced from the virtual namespace.
+func translateRuleItems(vItems []storkv1alpha1.RuleItem, vNamespace string) []storkv1alpha1.RuleItem {
+	pItems := []storkv1alpha1.RuleItem{}
+	for _, vItem := range vItems {
+		pItem := *vItem.DeepCopy()
+		pItem.PodSelector = map[string]string{
+			translate.MarkerLabel:    translate.Suffix,
+			translate.NamespaceLabel: vNamespace,
+		}
+		for k, v := range vItem.PodSelector {
+			pItem.PodSelector[translator.ConvertLabelKey(k)] = v
+		}
+		pItems = append(pItems, pItem)
+	}
+	return pItems
+}
+
+// translateSnapshotRuleAnnotations points the pre and post snapshot rule annotations of
+// a snapshot to the host rules of the virtual namespace.
+func translateSnapshotRuleAnnotations(annotations map[string]string, vNamespace string) {
+	for _, key := range []string{storkv1alpha1.PreSnapshotRuleAnnotation, storkv1alpha1.PostSnapshotRuleAnnotation} {
+		if name := annotations[key]; name != "" {
+			annotations[key] = translate.PhysicalName(name, vNamespace)
+		}
+	}
+}
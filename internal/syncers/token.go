@@ -0,0 +1,191 @@
+package syncers
+
+import (
+	"context"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/portworx/pxe-vcluster/internal/config"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+	"github.com/portworx/pxe-vcluster/internal/pxauth"
+)
+
+const (
+	// TokenSecretName is the name of the host Secret holding the Portworx token of the
+	// vcluster. It lives in the vcluster namespace, so storage classes can reference it
+	// with csi.storage.k8s.io/provisioner-secret-name: px-user-token and
+	// csi.storage.k8s.io/provisioner-secret-namespace: ${pvc.namespace}.
+	TokenSecretName = "px-user-token"
+	// TokenExpiryAnnotation holds the expiry of the token in the token Secret.
+	TokenExpiryAnnotation = "pxe.portworx.io/token-expiry"
+
+	tokenSecretKey     = "auth-token"
+	tokenCheckInterval = time.Minute
+
+	// annotations used by Portworx to find the token of a PVC or snapshot
+	pxAuthSecretNameAnnotation      = "openstorage.io/auth-secret-name"
+	pxAuthSecretNamespaceAnnotation = "openstorage.io/auth-secret-namespace"
+)
+
+// NewTokenProvisioner returns a controller which mints a Portworx token for the vcluster
+// into the host Secret TokenSecretName and renews it once two thirds of its lifetime
+// passed. A token Secret created by an admin is projected as is and never touched.
+func NewTokenProvisioner(
+	ctx *synccontext.RegisterContext,
+	issuer pxauth.Issuer,
+	roles []string,
+	groups []string,
+	lifetime time.Duration,
+) syncer.Base {
+	if len(groups) == 0 {
+		groups = []string{translate.Suffix}
+	}
+
+	return &tokenProvisioner{
+		issuer: issuer,
+		claims: pxauth.Claims{
+			Subject: "vcluster/" + ctx.TargetNamespace + "/" + translate.Suffix,
+			Name:    translate.Suffix,
+			Roles:   roles,
+			Groups:  groups,
+		},
+		lifetime: lifetime,
+		now:      time.Now,
+		log:      log.New(config.TokenSyncerName),
+	}
+}
+
+type tokenProvisioner struct {
+	issuer   pxauth.Issuer
+	claims   pxauth.Claims
+	lifetime time.Duration
+	now      func() time.Time
+	log      log.Logger
+
+	targetNamespace string
+	physicalReader  client.Reader
+	physicalClient  client.Client
+}
+
+func (p *tokenProvisioner) Name() string {
+	return config.TokenSyncerName
+}
+
+var _ syncer.ControllerStarter = &tokenProvisioner{}
+
+func (p *tokenProvisioner) Register(ctx *synccontext.RegisterContext) error {
+	p.targetNamespace = ctx.TargetNamespace
+	// the caches are not started yet, so read the secret directly
+	p.physicalReader = ctx.PhysicalManager.GetAPIReader()
+	p.physicalClient = ctx.PhysicalManager.GetClient()
+
+	// make sure a token exists before the first PVC is synced
+	if err := p.ensureToken(ctx.Context); err != nil {
+		return errors.Wrap(err, "ensure portworx token")
+	}
+
+	go wait.UntilWithContext(ctx.Context, func(ctx context.Context) {
+		if err := p.ensureToken(ctx); err != nil {
+			p.log.Errorf("error ensuring portworx token: %v", err)
+		}
+	}, tokenCheckInterval)
+
+	return nil
+}
+
+func (p *tokenProvisioner) ensureToken(ctx context.Context) error {
+	secret := &corev1.Secret{}
+	if err := p.physicalReader.Get(ctx, client.ObjectKey{
+		Namespace: p.targetNamespace,
+		Name:      TokenSecretName,
+	}, secret); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return errors.Wrap(err, "get token secret")
+		}
+		secret = nil
+	}
+
+	if secret != nil {
+		// projected by an admin
+		if secret.Labels[provenance.VClusterKey] != translate.Suffix {
+			return nil
+		}
+		if !p.needsRenewal(secret) {
+			return nil
+		}
+	}
+
+	token, expiry, err := p.issuer.Issue(p.claims, p.lifetime)
+	if err != nil {
+		return errors.Wrap(err, "issue token")
+	}
+
+	if secret == nil {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: p.targetNamespace,
+				Name:      TokenSecretName,
+				Labels:    provenance.Owner{VCluster: translate.Suffix}.Labels(),
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+		setToken(secret, token, expiry)
+		if err := p.physicalClient.Create(ctx, secret); err != nil {
+			return errors.Wrap(err, "create token secret")
+		}
+	} else {
+		setToken(secret, token, expiry)
+		if err := p.physicalClient.Update(ctx, secret); err != nil {
+			return errors.Wrap(err, "update token secret")
+		}
+	}
+
+	p.log.Infof("Issued portworx token valid until %s", expiry.Format(time.RFC3339))
+	return nil
+}
+
+func (p *tokenProvisioner) needsRenewal(secret *corev1.Secret) bool {
+	if len(secret.Data[tokenSecretKey]) == 0 {
+		return true
+	}
+
+	expiry, err := time.Parse(time.RFC3339, secret.Annotations[TokenExpiryAnnotation])
+	if err != nil {
+		return true
+	}
+
+	return expiry.Sub(p.now()) < p.lifetime/3
+}
+
+func setToken(secret *corev1.Secret, token string, expiry time.Time) {
+	secret.Annotations = provenance.Merge(secret.Annotations, map[string]string{
+		TokenExpiryAnnotation: expiry.Format(time.RFC3339),
+	})
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[tokenSecretKey] = []byte(token)
+}
+
+// injectTokenSecret points Portworx to the token Secret of the vcluster. References set
+// by tenants are always dropped, so a tenant can't use the token of someone else.
+func injectTokenSecret(annotations map[string]string, tokenSecret, targetNamespace string) {
+	if tokenSecret == "" {
+		delete(annotations, pxAuthSecretNameAnnotation)
+		delete(annotations, pxAuthSecretNamespaceAnnotation)
+		return
+	}
+
+	annotations[pxAuthSecretNameAnnotation] = tokenSecret
+	annotations[pxAuthSecretNamespaceAnnotation] = targetNamespace
+}
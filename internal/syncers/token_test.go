@@ -0,0 +1,163 @@
+package syncers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/portworx/pxe-vcluster/internal/pxauth"
+)
+
+func TestTokenNeedsRenewal(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	p := &tokenProvisioner{lifetime: 24 * time.Hour, now: func() time.Time { return now }}
+
+	tests := []struct {
+		name     string
+		token    string
+		expiry   string
+		expected bool
+	}{
+		{
+			name:     "no token",
+			expiry:   now.Add(24 * time.Hour).Format(time.RFC3339),
+			expected: true,
+		},
+		{
+			name:     "no expiry",
+			token:    "token",
+			expected: true,
+		},
+		{
+			name:     "invalid expiry",
+			token:    "token",
+			expiry:   "tomorrow",
+			expected: true,
+		},
+		{
+			name:   "fresh token",
+			token:  "token",
+			expiry: now.Add(24 * time.Hour).Format(time.RFC3339),
+		},
+		{
+			name:   "more than a third of the lifetime left",
+			token:  "token",
+			expiry: now.Add(8*time.Hour + time.Second).Format(time.RFC3339),
+		},
+		{
+			name:     "less than a third of the lifetime left",
+			token:    "token",
+			expiry:   now.Add(8*time.Hour - time.Second).Format(time.RFC3339),
+			expected: true,
+		},
+		{
+			name:     "expired token",
+			token:    "token",
+			expiry:   now.Add(-time.Hour).Format(time.RFC3339),
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+				Data:       map[string][]byte{},
+			}
+			if tt.token != "" {
+				secret.Data[tokenSecretKey] = []byte(tt.token)
+			}
+			if tt.expiry != "" {
+				secret.Annotations[TokenExpiryAnnotation] = tt.expiry
+			}
+
+			if got := p.needsRenewal(secret); got != tt.expected {
+				t.Errorf("expected needsRenewal %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTokenRotation(t *testing.T) {
+	translate.Suffix = "vcluster"
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	clock := func() time.Time { return now }
+
+	issuer := &pxauth.FakeIssuer{Now: clock}
+	physicalClient := fake.NewClientBuilder().Build()
+	p := &tokenProvisioner{
+		issuer:          issuer,
+		claims:          pxauth.Claims{Subject: "vcluster/vcluster-ns/vcluster"},
+		lifetime:        24 * time.Hour,
+		now:             clock,
+		log:             log.New("test"),
+		targetNamespace: "vcluster-ns",
+		physicalReader:  physicalClient,
+		physicalClient:  physicalClient,
+	}
+
+	steps := []struct {
+		name          string
+		elapsed       time.Duration
+		expectedToken string
+	}{
+		{name: "create token", elapsed: 0, expectedToken: "fake-token-vcluster/vcluster-ns/vcluster-1"},
+		{name: "keep fresh token", elapsed: time.Hour, expectedToken: "fake-token-vcluster/vcluster-ns/vcluster-1"},
+		{name: "keep token with a third of its lifetime left", elapsed: 16 * time.Hour, expectedToken: "fake-token-vcluster/vcluster-ns/vcluster-1"},
+		{name: "renew token after two thirds of its lifetime", elapsed: 16*time.Hour + time.Minute, expectedToken: "fake-token-vcluster/vcluster-ns/vcluster-2"},
+		{name: "keep renewed token", elapsed: 17 * time.Hour, expectedToken: "fake-token-vcluster/vcluster-ns/vcluster-2"},
+	}
+
+	for _, step := range steps {
+		now = start.Add(step.elapsed)
+		if err := p.ensureToken(context.Background()); err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+
+		secret := &corev1.Secret{}
+		if err := physicalClient.Get(context.Background(), client.ObjectKey{Namespace: "vcluster-ns", Name: TokenSecretName}, secret); err != nil {
+			t.Fatalf("%s: get token secret: %v", step.name, err)
+		}
+		if got := string(secret.Data[tokenSecretKey]); got != step.expectedToken {
+			t.Errorf("%s: expected token %s, got %s", step.name, step.expectedToken, got)
+		}
+	}
+
+	if len(issuer.Issued()) != 2 {
+		t.Errorf("expected 2 issued tokens, got %d", len(issuer.Issued()))
+	}
+}
+
+func TestTokenSecretOfAdminIsNotTouched(t *testing.T) {
+	translate.Suffix = "vcluster"
+	issuer := &pxauth.FakeIssuer{}
+	physicalClient := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vcluster-ns", Name: TokenSecretName},
+		Data:       map[string][]byte{tokenSecretKey: []byte("admin-token")},
+	}).Build()
+	p := &tokenProvisioner{
+		issuer:          issuer,
+		claims:          pxauth.Claims{Subject: "vcluster/vcluster-ns/vcluster"},
+		lifetime:        24 * time.Hour,
+		now:             time.Now,
+		log:             log.New("test"),
+		targetNamespace: "vcluster-ns",
+		physicalReader:  physicalClient,
+		physicalClient:  physicalClient,
+	}
+
+	if err := p.ensureToken(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issuer.Issued()) != 0 {
+		t.Errorf("expected no issued tokens, got %d", len(issuer.Issued()))
+	}
+}
@@ -1,17 +1,25 @@
 package syncers
 
 import (
+	"time"
+
 	"github.com/loft-sh/vcluster-sdk/plugin"
 	"github.com/loft-sh/vcluster-sdk/syncer"
 	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
 	"github.com/loft-sh/vcluster-sdk/syncer/translator"
 	"github.com/loft-sh/vcluster-sdk/translate"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/policy"
 )
 
 func init() {
@@ -19,8 +27,18 @@ func init() {
 	_ = snapshotv1.AddToScheme(plugin.Scheme)
 }
 
-func NewSnapshotSyncer(ctx *synccontext.RegisterContext) syncer.Base {
+func NewSnapshotSyncer(
+	ctx *synccontext.RegisterContext,
+	tokenSecret string,
+	pendingTimeout time.Duration,
+	namespaceSelector labels.Selector,
+	metadataPolicy *policy.MetadataPolicy,
+) syncer.Base {
 	return &snapshotSyncer{
+		tokenSecret:    tokenSecret,
+		pendingTimeout: pendingTimeout,
+		scope:          snapshotScope{namespaceSelector: namespaceSelector},
+		metadataPolicy: metadataPolicy,
 		NamespacedTranslator: translator.NewNamespacedTranslator(
 			ctx,
 			"volumesnapshot",
@@ -31,6 +49,11 @@ func NewSnapshotSyncer(ctx *synccontext.RegisterContext) syncer.Base {
 
 type snapshotSyncer struct {
 	translator.NamespacedTranslator
+
+	tokenSecret    string
+	pendingTimeout time.Duration
+	scope          snapshotScope
+	metadataPolicy *policy.MetadataPolicy
 }
 
 var _ syncer.Initializer = &snapshotSyncer{}
@@ -48,20 +71,100 @@ func (s *snapshotSyncer) Init(ctx *synccontext.RegisterContext) error {
 	return nil
 }
 
+var _ syncer.ControllerModifier = &snapshotSyncer{}
+
+// ModifyController re-syncs the snapshots of a namespace when its labels change, which
+// may change whether its snapshots are synced.
+func (s *snapshotSyncer) ModifyController(ctx *synccontext.RegisterContext, builder *builder.Builder) (*builder.Builder, error) {
+	if s.scope.namespaceSelector == nil || s.scope.namespaceSelector.Empty() {
+		return builder, nil
+	}
+
+	virtualClient := ctx.VirtualManager.GetClient()
+	return builder.Watches(
+		&source.Kind{Type: &corev1.Namespace{}},
+		handler.EnqueueRequestsFromMapFunc(snapshotsInNamespace(virtualClient)),
+	), nil
+}
+
+// SyncDown creates the host snapshot stamped with the provenance of the virtual one. Stork
+// copies the labels of a VolumeSnapshot to the Portworx snapshot it takes, which makes
+// the provenance visible on the Portworx snapshot as well.
 func (s *snapshotSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
-	return s.SyncDownCreate(ctx, vObj, s.TranslateMetadata(vObj).(*snapshotv1.VolumeSnapshot))
+	if isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+
+	// imported snapshots are owned by the host and are never synced down
+	if isImported(vObj) {
+		return ctrl.Result{}, nil
+	}
+	if ignored, err := s.ignored(ctx, vObj.(*snapshotv1.VolumeSnapshot)); ignored || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	pObj := translateMetadata(s, s.filterMetadata(vObj)).(*snapshotv1.VolumeSnapshot)
+	err := s.translateSecretAnnotations(ctx, vObj, pObj.Annotations)
+	if err == nil {
+		err = s.translateStorkAnnotations(ctx, vObj, pObj.Annotations)
+	}
+	if err != nil {
+		if escalateErr := s.checkNotSynced(ctx, vObj.(*snapshotv1.VolumeSnapshot), err); escalateErr != nil {
+			ctx.Log.Infof("error escalating snapshot error: %v", escalateErr)
+		}
+		return ctrl.Result{}, err
+	}
+	injectTokenSecret(pObj.Annotations, s.tokenSecret, ctx.TargetNamespace)
+
+	return s.SyncDownCreate(ctx, vObj, pObj)
 }
 
 func (s *snapshotSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
-	return s.SyncDownUpdate(ctx, vObj, s.translateUpdate(pObj.(*snapshotv1.VolumeSnapshot), vObj.(*snapshotv1.VolumeSnapshot)))
+	if isPaused(pObj) || isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	if isImported(vObj) {
+		return ctrl.Result{}, nil
+	}
+	if shouldRelease(pObj, vObj) {
+		return releasePhysical(ctx, pObj, vObj)
+	}
+
+	pSnapshot := pObj.(*snapshotv1.VolumeSnapshot)
+	vSnapshot := vObj.(*snapshotv1.VolumeSnapshot)
+	if ignored, err := s.ignored(ctx, vSnapshot); ignored || err != nil {
+		return ctrl.Result{}, err
+	}
+	updated, err := s.translateUpdate(ctx, pSnapshot, vSnapshot)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	result, err := s.SyncDownUpdate(ctx, vObj, updated)
+	if err != nil {
+		return result, err
+	}
+
+	return s.checkPending(ctx, pSnapshot, vSnapshot)
 }
 
-func (s *snapshotSyncer) translateUpdate(pObj, vObj *snapshotv1.VolumeSnapshot) *snapshotv1.VolumeSnapshot {
+func (s *snapshotSyncer) translateUpdate(
+	ctx *synccontext.SyncContext,
+	pObj, vObj *snapshotv1.VolumeSnapshot,
+) (*snapshotv1.VolumeSnapshot, error) {
 	var updated *snapshotv1.VolumeSnapshot
 
 	// check annotations & labels
-	changed, updatedAnnotations, updatedLabels := s.TranslateMetadataUpdate(vObj, pObj)
-	if changed {
+	_, updatedAnnotations, updatedLabels := translateMetadataUpdate(s, s.filterMetadata(vObj), pObj)
+	if err := s.translateSecretAnnotations(ctx, vObj, updatedAnnotations); err != nil {
+		return nil, err
+	}
+	if err := s.translateStorkAnnotations(ctx, vObj, updatedAnnotations); err != nil {
+		return nil, err
+	}
+	injectTokenSecret(updatedAnnotations, s.tokenSecret, ctx.TargetNamespace)
+	if !equality.Semantic.DeepEqual(updatedAnnotations, pObj.Annotations) ||
+		!equality.Semantic.DeepEqual(updatedLabels, pObj.Labels) {
 		updated = newSnapshotIfNil(updated, pObj)
 		updated.Labels = updatedLabels
 		updated.Annotations = updatedAnnotations
@@ -73,7 +176,59 @@ func (s *snapshotSyncer) translateUpdate(pObj, vObj *snapshotv1.VolumeSnapshot)
 		updated.Spec = vObj.Spec
 	}
 
-	return updated
+	return updated, nil
+}
+
+// translateSecretAnnotations rewrites the secret annotations of encrypted snapshots and
+// reports references to Secrets outside of the vcluster on the virtual object.
+func (s *snapshotSyncer) translateSecretAnnotations(
+	ctx *synccontext.SyncContext,
+	vObj client.Object,
+	annotations map[string]string,
+) error {
+	err := translateSecretAnnotations(
+		ctx.Context,
+		ctx.VirtualClient,
+		ctx.PhysicalClient,
+		ctx.TargetNamespace,
+		vObj.GetNamespace(),
+		annotations,
+	)
+	if err != nil {
+		s.EventRecorder().Eventf(vObj, "Warning", "SyncError", "Invalid encryption secret: %v", err)
+	}
+
+	return err
+}
+
+// translateStorkAnnotations validates the Stork and Portworx annotations of the snapshot
+// and points the rule annotations to the host rules.
+func (s *snapshotSyncer) translateStorkAnnotations(
+	ctx *synccontext.SyncContext,
+	vObj client.Object,
+	annotations map[string]string,
+) error {
+	err := validateStorkAnnotations(
+		ctx.Context,
+		ctx.VirtualClient,
+		s.metadataPolicy,
+		ctx.TargetNamespace,
+		vObj.GetNamespace(),
+		annotations,
+	)
+	if err != nil {
+		s.EventRecorder().Eventf(vObj, "Warning", "SyncError", "Invalid snapshot annotations: %v", err)
+		return err
+	}
+
+	translateSnapshotRuleAnnotations(annotations, vObj.GetNamespace())
+	return nil
+}
+
+// filterMetadata removes the labels and annotations which the metadata policy doesn't
+// sync down.
+func (s *snapshotSyncer) filterMetadata(vObj client.Object) client.Object {
+	return filterMetadata(s.metadataPolicy.Rules("VolumeSnapshot").Down, vObj)
 }
 
 func newSnapshotIfNil(updated *snapshotv1.VolumeSnapshot, pObj *snapshotv1.VolumeSnapshot) *snapshotv1.VolumeSnapshot {
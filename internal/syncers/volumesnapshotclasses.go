@@ -0,0 +1,224 @@
+package syncers
+
+import (
+	"strings"
+
+	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/policy"
+)
+
+func init() {
+	// Make sure the CSI snapshot scheme is registered
+	_ = csisnapshotv1.AddToScheme(plugin.Scheme)
+}
+
+// NewSnapshotClassSyncer returns a syncer which imports the Portworx VolumeSnapshotClasses
+// of the host cluster into the virtual cluster. Imported classes are read-only, changes
+// made inside the vcluster are reverted. If allowlist is not empty, only the listed
+// classes are imported. Labels and annotations are filtered by the metadata policy.
+func NewSnapshotClassSyncer(
+	ctx *synccontext.RegisterContext,
+	allowlist []string,
+	metadataPolicy *policy.MetadataPolicy,
+) syncer.Base {
+	allowed := map[string]bool{}
+	for _, name := range allowlist {
+		allowed[name] = true
+	}
+
+	return &snapshotClassSyncer{
+		Translator: translator.NewMirrorPhysicalTranslator(
+			"volumesnapshotclass",
+			&csisnapshotv1.VolumeSnapshotClass{},
+		),
+		allowed: allowed,
+		filter:  metadataPolicy.Rules("VolumeSnapshotClass").Up,
+	}
+}
+
+type snapshotClassSyncer struct {
+	translator.Translator
+
+	allowed map[string]bool
+	filter  policy.MetadataFilter
+}
+
+var _ syncer.Initializer = &snapshotClassSyncer{}
+
+func (s *snapshotClassSyncer) Init(ctx *synccontext.RegisterContext) error {
+	if err := translate.EnsureCRDFromPhysicalCluster(
+		ctx.Context,
+		ctx.PhysicalManager.GetConfig(),
+		ctx.VirtualManager.GetConfig(),
+		csisnapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshotClass"),
+	); err != nil {
+		return errors.Wrap(err, "ensure CRD VolumeSnapshotClass from physical cluster")
+	}
+
+	return nil
+}
+
+// IsManaged returns true for all host classes of a Portworx driver, which are allowed to
+// be imported.
+func (s *snapshotClassSyncer) IsManaged(pObj client.Object) (bool, error) {
+	pClass, ok := pObj.(*csisnapshotv1.VolumeSnapshotClass)
+	if !ok {
+		return false, nil
+	}
+
+	if pClass.Driver != snapshotv1.PortworxCsiProvisionerName &&
+		pClass.Driver != snapshotv1.PortworxCsiDeprecatedProvisionerName {
+		return false, nil
+	}
+
+	return len(s.allowed) == 0 || s.allowed[pClass.Name], nil
+}
+
+var _ syncer.UpSyncer = &snapshotClassSyncer{}
+
+func (s *snapshotClassSyncer) SyncUp(ctx *synccontext.SyncContext, pObj client.Object) (ctrl.Result, error) {
+	vClass := s.translate(pObj.(*csisnapshotv1.VolumeSnapshotClass))
+
+	ctx.Log.Infof("import volume snapshot class %s", vClass.Name)
+	if err := ctx.VirtualClient.Create(ctx.Context, vClass); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "create virtual volume snapshot class")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (s *snapshotClassSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
+	// classes created inside the vcluster are never synced down, imported classes can't
+	// be paused by tenants
+	if !isImported(vObj) {
+		return ctrl.Result{}, nil
+	}
+
+	return s.deleteVirtual(ctx, vObj, "host volume snapshot class was deleted")
+}
+
+func (s *snapshotClassSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	// only the host class pauses the sync, the virtual class is imported and the paused
+	// annotation of tenants is ignored like any other change
+	if isPaused(pObj) {
+		return ctrl.Result{}, nil
+	}
+
+	// the virtual class of an allowed host class is always the imported one, whatever its
+	// annotations say, so tenants can't take over imported classes by editing them
+	managed, err := s.IsManaged(pObj)
+	if err != nil {
+		return ctrl.Result{}, err
+	} else if !managed {
+		if !isImported(vObj) {
+			return ctrl.Result{}, nil
+		}
+		return s.deleteVirtual(ctx, vObj, "host volume snapshot class is not allowed anymore")
+	}
+
+	updated := s.translateUpdate(pObj.(*csisnapshotv1.VolumeSnapshotClass), vObj.(*csisnapshotv1.VolumeSnapshotClass))
+	if updated == nil {
+		return ctrl.Result{}, nil
+	}
+
+	ctx.Log.Infof("update imported volume snapshot class %s", updated.Name)
+	if err := ctx.VirtualClient.Update(ctx.Context, updated); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "update virtual volume snapshot class")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (s *snapshotClassSyncer) deleteVirtual(ctx *synccontext.SyncContext, vObj client.Object, reason string) (ctrl.Result, error) {
+	ctx.Log.Infof("delete imported volume snapshot class %s, because %s", vObj.GetName(), reason)
+	if err := ctx.VirtualClient.Delete(ctx.Context, vObj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (s *snapshotClassSyncer) translate(pClass *csisnapshotv1.VolumeSnapshotClass) *csisnapshotv1.VolumeSnapshotClass {
+	vClass := s.TranslateMetadata(pClass).(*csisnapshotv1.VolumeSnapshotClass)
+	vClass.Labels = s.filter.Apply(vClass.Labels)
+	vClass.Annotations = importedAnnotations(pClass, s.filter)
+	vClass.Parameters = filterSecretParameters(pClass.Parameters)
+	return vClass
+}
+
+func (s *snapshotClassSyncer) translateUpdate(pClass, vClass *csisnapshotv1.VolumeSnapshotClass) *csisnapshotv1.VolumeSnapshotClass {
+	translated := s.translate(pClass)
+	if equality.Semantic.DeepEqual(translated.Labels, vClass.Labels) &&
+		equality.Semantic.DeepEqual(translated.Annotations, vClass.Annotations) &&
+		equality.Semantic.DeepEqual(translated.Parameters, vClass.Parameters) &&
+		translated.Driver == vClass.Driver &&
+		translated.DeletionPolicy == vClass.DeletionPolicy {
+		return nil
+	}
+
+	updated := vClass.DeepCopy()
+	updated.Labels = translated.Labels
+	updated.Annotations = translated.Annotations
+	updated.Parameters = translated.Parameters
+	updated.Driver = translated.Driver
+	updated.DeletionPolicy = translated.DeletionPolicy
+	return updated
+}
+
+// importedAnnotations returns the annotations of the host object the filter allows with
+// the import marker. Annotations which may hold secrets are dropped before the filter
+// applies, whatever the metadata policy allows.
+func importedAnnotations(pObj client.Object, filter policy.MetadataFilter) map[string]string {
+	annotations := map[string]string{}
+	for k, v := range filter.Apply(filterSecretAnnotations(pObj.GetAnnotations())) {
+		annotations[k] = v
+	}
+
+	annotations[ImportedFromAnnotation] = pObj.GetName()
+	if pObj.GetNamespace() != "" {
+		annotations[ImportedFromAnnotation] = pObj.GetNamespace() + "/" + pObj.GetName()
+	}
+	return annotations
+}
+
+// filterSecretAnnotations drops the last applied configuration, which holds the secret
+// parameters of the object, and all annotations referencing secrets.
+func filterSecretAnnotations(annotations map[string]string) map[string]string {
+	filtered := map[string]string{}
+	for k, v := range annotations {
+		if k == corev1.LastAppliedConfigAnnotation || strings.Contains(strings.ToLower(k), "secret") {
+			continue
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
+
+// filterSecretParameters drops all parameters referencing secrets, e.g.
+// csi.storage.k8s.io/snapshotter-secret-name, which tenants must not see.
+func filterSecretParameters(parameters map[string]string) map[string]string {
+	if parameters == nil {
+		return nil
+	}
+
+	filtered := map[string]string{}
+	for k, v := range parameters {
+		if strings.Contains(strings.ToLower(k), "secret") {
+			continue
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
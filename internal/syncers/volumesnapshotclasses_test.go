@@ -0,0 +1,67 @@
+package syncers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/portworx/pxe-vcluster/internal/policy"
+)
+
+func TestImportedAnnotations(t *testing.T) {
+	pClass := &csisnapshotv1.VolumeSnapshotClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "px-csi-snapclass",
+			Annotations: map[string]string{
+				corev1.LastAppliedConfigAnnotation:                `{"parameters":{"csi.storage.k8s.io/snapshotter-secret-name":"px-admin"}}`,
+				"example.com/snapshotter-secret-name":             "px-admin",
+				"snapshot.storage.kubernetes.io/is-default-class": "true",
+				"example.com/team":                                "storage",
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filter   policy.MetadataFilter
+		expected map[string]string
+	}{
+		{
+			name: "no filter",
+			expected: map[string]string{
+				"snapshot.storage.kubernetes.io/is-default-class": "true",
+				"example.com/team":     "storage",
+				ImportedFromAnnotation: "px-csi-snapclass",
+			},
+		},
+		{
+			name:   "filter allowing secrets",
+			filter: policy.MetadataFilter{Allow: []string{"*"}},
+			expected: map[string]string{
+				"snapshot.storage.kubernetes.io/is-default-class": "true",
+				"example.com/team":     "storage",
+				ImportedFromAnnotation: "px-csi-snapclass",
+			},
+		},
+		{
+			name:   "filter denying keys",
+			filter: policy.MetadataFilter{Deny: []string{"example.com/*"}},
+			expected: map[string]string{
+				"snapshot.storage.kubernetes.io/is-default-class": "true",
+				ImportedFromAnnotation:                            "px-csi-snapclass",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := importedAnnotations(pClass, tt.filter)
+			if diff := cmp.Diff(tt.expected, annotations); diff != "" {
+				t.Errorf("unexpected annotations (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
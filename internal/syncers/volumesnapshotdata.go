@@ -7,14 +7,22 @@ import (
 	"github.com/loft-sh/vcluster-sdk/translate"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/policy"
 )
 
-func NewSnapshotDataSyncer(ctx *synccontext.RegisterContext) syncer.Base {
+func NewSnapshotDataSyncer(
+	ctx *synccontext.RegisterContext,
+	namespaceSelector labels.Selector,
+	metadataPolicy *policy.MetadataPolicy,
+) syncer.Base {
 	return &snapshotDataSyncer{
+		scope:  snapshotScope{namespaceSelector: namespaceSelector},
+		filter: metadataPolicy.Rules("VolumeSnapshotData").Down,
 		NamespacedTranslator: translator.NewNamespacedTranslator(
 			ctx,
 			"volumesnapshotdata",
@@ -25,6 +33,9 @@ func NewSnapshotDataSyncer(ctx *synccontext.RegisterContext) syncer.Base {
 
 type snapshotDataSyncer struct {
 	translator.NamespacedTranslator
+
+	scope  snapshotScope
+	filter policy.MetadataFilter
 }
 
 var _ syncer.Initializer = &snapshotDataSyncer{}
@@ -43,10 +54,35 @@ func (s *snapshotDataSyncer) Init(ctx *synccontext.RegisterContext) error {
 }
 
 func (s *snapshotDataSyncer) SyncDown(ctx *synccontext.SyncContext, vObj client.Object) (ctrl.Result, error) {
-	return s.SyncDownCreate(ctx, vObj, s.TranslateMetadata(vObj).(*snapshotv1.VolumeSnapshotData))
+	if isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+
+	// snapshot data created by the csi bridge points at host snapshots and is never synced down
+	if isImported(vObj) {
+		return ctrl.Result{}, nil
+	}
+	if ignored, err := s.ignored(ctx, vObj.(*snapshotv1.VolumeSnapshotData)); ignored || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return s.SyncDownCreate(ctx, vObj, translateMetadata(s, filterMetadata(s.filter, vObj)).(*snapshotv1.VolumeSnapshotData))
 }
 
 func (s *snapshotDataSyncer) Sync(ctx *synccontext.SyncContext, pObj client.Object, vObj client.Object) (ctrl.Result, error) {
+	if isPaused(pObj) || isPaused(vObj) {
+		return ctrl.Result{}, nil
+	}
+	if isImported(vObj) {
+		return ctrl.Result{}, nil
+	}
+	if shouldRelease(pObj, vObj) {
+		return releasePhysical(ctx, pObj, vObj)
+	}
+	if ignored, err := s.ignored(ctx, vObj.(*snapshotv1.VolumeSnapshotData)); ignored || err != nil {
+		return ctrl.Result{}, err
+	}
+
 	return s.SyncDownUpdate(
 		ctx,
 		vObj,
@@ -58,7 +94,7 @@ func (s *snapshotDataSyncer) translateUpdate(pObj, vObj *snapshotv1.VolumeSnapsh
 	var updated *snapshotv1.VolumeSnapshotData
 
 	// check annotations & labels
-	changed, updatedAnnotations, updatedLabels := s.TranslateMetadataUpdate(vObj, pObj)
+	changed, updatedAnnotations, updatedLabels := translateMetadataUpdate(s, filterMetadata(s.filter, vObj), pObj)
 	if changed {
 		updated = newSnapshotDataIfNil(updated, pObj)
 		updated.Labels = updatedLabels
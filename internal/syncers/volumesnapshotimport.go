@@ -0,0 +1,226 @@
+package syncers
+
+import (
+	"context"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/policy"
+	"github.com/portworx/pxe-vcluster/internal/provenance"
+)
+
+const (
+	// ImportedFromAnnotation is set on virtual objects which were imported from the host
+	// and holds the namespace/name (or name) of the host object.
+	ImportedFromAnnotation = "pxe.portworx.io/imported-from"
+	// ImportedAnnotation is set on host snapshots which were imported into a vcluster.
+	ImportedAnnotation = "pxe.portworx.io/imported"
+)
+
+// NewSnapshotImporter returns a controller which imports host VolumeSnapshots created
+// outside the vcluster (e.g. by admins or snapshot schedules) for PVCs of the vcluster.
+// Imported snapshots are read-only mirrors and are never synced down. Labels and
+// annotations are filtered by the metadata policy.
+func NewSnapshotImporter(
+	ctx *synccontext.RegisterContext,
+	interval time.Duration,
+	metadataPolicy *policy.MetadataPolicy,
+) syncer.Base {
+	return &snapshotImporter{
+		interval: interval,
+		filter:   metadataPolicy.Rules("VolumeSnapshot").Up,
+		log:      log.New("volumesnapshot-importer"),
+	}
+}
+
+type snapshotImporter struct {
+	interval time.Duration
+	filter   policy.MetadataFilter
+	log      log.Logger
+
+	targetNamespace string
+	virtualClient   client.Client
+	physicalClient  client.Client
+}
+
+func (s *snapshotImporter) Name() string {
+	return "volumesnapshot-importer"
+}
+
+var _ syncer.ControllerStarter = &snapshotImporter{}
+
+func (s *snapshotImporter) Register(ctx *synccontext.RegisterContext) error {
+	s.targetNamespace = ctx.TargetNamespace
+	s.virtualClient = ctx.VirtualManager.GetClient()
+	s.physicalClient = ctx.PhysicalManager.GetClient()
+
+	go wait.UntilWithContext(ctx.Context, func(ctx context.Context) {
+		if err := s.importSnapshots(ctx); err != nil {
+			s.log.Errorf("error importing host volume snapshots: %v", err)
+		}
+	}, s.interval)
+
+	return nil
+}
+
+func (s *snapshotImporter) importSnapshots(ctx context.Context) error {
+	pSnapshots := &snapshotv1.VolumeSnapshotList{}
+	if err := s.physicalClient.List(ctx, pSnapshots, client.InNamespace(s.targetNamespace)); err != nil {
+		return errors.Wrap(err, "list host volume snapshots")
+	}
+
+	for i := range pSnapshots.Items {
+		pSnapshot := &pSnapshots.Items[i]
+		if translate.IsManaged(pSnapshot) || isPaused(pSnapshot) {
+			continue
+		}
+
+		if err := s.importSnapshot(ctx, pSnapshot); err != nil {
+			s.log.Errorf("error importing host volume snapshot %s/%s: %v", pSnapshot.Namespace, pSnapshot.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *snapshotImporter) importSnapshot(ctx context.Context, pSnapshot *snapshotv1.VolumeSnapshot) error {
+	if pSnapshot.Annotations[ImportedAnnotation] == "true" {
+		return s.updateImportedSnapshot(ctx, pSnapshot)
+	}
+
+	vPVC, err := s.virtualPVC(ctx, pSnapshot.Spec.PersistentVolumeClaimName)
+	if err != nil || vPVC == nil {
+		return err
+	}
+
+	vSnapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vPVC.Namespace,
+			Name:      pSnapshot.Name,
+			Labels:    s.filter.Apply(pSnapshot.Labels),
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			PersistentVolumeClaimName: vPVC.Name,
+		},
+		Status: *pSnapshot.Status.DeepCopy(),
+	}
+	vSnapshot.Annotations = importedAnnotations(pSnapshot, s.filter)
+
+	s.log.Infof("import host volume snapshot %s/%s as %s/%s", pSnapshot.Namespace, pSnapshot.Name, vSnapshot.Namespace, vSnapshot.Name)
+	if err := s.virtualClient.Create(ctx, vSnapshot); err != nil {
+		if !kerrors.IsAlreadyExists(err) {
+			return errors.Wrap(err, "create virtual volume snapshot")
+		}
+
+		// a previous import may have created the virtual snapshot and failed to mark the
+		// host snapshot, in which case marking it is retried
+		existing, err := s.previousImport(ctx, pSnapshot, vSnapshot)
+		if err != nil || existing == nil {
+			return err
+		}
+		vSnapshot = existing
+	}
+
+	// remember the import on the host snapshot, so it isn't imported again after the
+	// tenant deleted the virtual one
+	patch := client.MergeFrom(pSnapshot.DeepCopy())
+	provenance.Stamp(pSnapshot, provenance.OwnerOf(vSnapshot))
+	pSnapshot.Annotations[ImportedAnnotation] = "true"
+	if err := s.physicalClient.Patch(ctx, pSnapshot, patch); err != nil {
+		return errors.Wrap(err, "mark host volume snapshot as imported")
+	}
+
+	return nil
+}
+
+// previousImport returns the existing virtual snapshot vSnapshot, if it was imported from
+// the host snapshot before, or nil if it is a different snapshot.
+func (s *snapshotImporter) previousImport(
+	ctx context.Context,
+	pSnapshot *snapshotv1.VolumeSnapshot,
+	vSnapshot *snapshotv1.VolumeSnapshot,
+) (*snapshotv1.VolumeSnapshot, error) {
+	existing := &snapshotv1.VolumeSnapshot{}
+	if err := s.virtualClient.Get(ctx, client.ObjectKeyFromObject(vSnapshot), existing); err != nil {
+		return nil, errors.Wrap(err, "get existing virtual volume snapshot")
+	}
+	if existing.Annotations[ImportedFromAnnotation] != vSnapshot.Annotations[ImportedFromAnnotation] ||
+		existing.Spec.PersistentVolumeClaimName != vSnapshot.Spec.PersistentVolumeClaimName {
+		s.log.Infof("skip importing host volume snapshot %s/%s, because virtual volume snapshot %s/%s already exists", pSnapshot.Namespace, pSnapshot.Name, vSnapshot.Namespace, vSnapshot.Name)
+		return nil, nil
+	}
+
+	s.log.Infof("retry marking host volume snapshot %s/%s as imported", pSnapshot.Namespace, pSnapshot.Name)
+	return existing, nil
+}
+
+// updateImportedSnapshot syncs the status of an imported host snapshot up to its virtual
+// counterpart.
+func (s *snapshotImporter) updateImportedSnapshot(ctx context.Context, pSnapshot *snapshotv1.VolumeSnapshot) error {
+	owner, ok := provenance.FromObject(pSnapshot)
+	if !ok || owner.VCluster != translate.Suffix {
+		return nil
+	}
+
+	vSnapshot := &snapshotv1.VolumeSnapshot{}
+	if err := s.virtualClient.Get(ctx, client.ObjectKey{Namespace: owner.Namespace, Name: owner.Name}, vSnapshot); err != nil {
+		// the virtual snapshot was deleted by the tenant
+		return client.IgnoreNotFound(err)
+	}
+	// the provenance of the host snapshot identifies the import, the annotation of the
+	// virtual snapshot can be edited by tenants
+	if vSnapshot.UID != owner.UID || equality.Semantic.DeepEqual(vSnapshot.Status, pSnapshot.Status) {
+		return nil
+	}
+
+	vSnapshot.Status = *pSnapshot.Status.DeepCopy()
+	if err := s.virtualClient.Update(ctx, vSnapshot); err != nil {
+		return errors.Wrap(err, "update virtual volume snapshot status")
+	}
+
+	return nil
+}
+
+// virtualPVC returns the virtual PVC of the given host PVC or nil, if the host PVC
+// doesn't belong to the vcluster.
+func (s *snapshotImporter) virtualPVC(ctx context.Context, pPVCName string) (*corev1.PersistentVolumeClaim, error) {
+	if pPVCName == "" {
+		return nil, nil
+	}
+
+	pPVC := &corev1.PersistentVolumeClaim{}
+	if err := s.physicalClient.Get(ctx, client.ObjectKey{Namespace: s.targetNamespace, Name: pPVCName}, pPVC); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if !translate.IsManaged(pPVC) || pPVC.Annotations[translator.NameAnnotation] == "" {
+		return nil, nil
+	}
+
+	vPVC := &corev1.PersistentVolumeClaim{}
+	if err := s.virtualClient.Get(ctx, client.ObjectKey{
+		Namespace: pPVC.Annotations[translator.NamespaceAnnotation],
+		Name:      pPVC.Annotations[translator.NameAnnotation],
+	}, vPVC); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	return vPVC, nil
+}
+
+// isImported returns true if the virtual object was imported from the host cluster.
+func isImported(vObj client.Object) bool {
+	return vObj.GetAnnotations()[ImportedFromAnnotation] != ""
+}
@@ -0,0 +1,194 @@
+package syncers
+
+import (
+	"fmt"
+	"time"
+
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+)
+
+// Reasons of the error condition set on virtual snapshots which didn't become ready.
+const (
+	reasonHostSnapshotError     = "HostSnapshotError"
+	reasonHostSnapshotNotSynced = "HostSnapshotNotCreated"
+	reasonHostPVCNotFound       = "HostPVCNotFound"
+	reasonHostPVCNotBound       = "HostPVCNotBound"
+	reasonControllerNotRunning  = "SnapshotControllerNotRunning"
+	reasonPendingTimeout        = "PendingTimeout"
+)
+
+const (
+	minPendingRequeue = 5 * time.Second
+	maxPendingRequeue = 2 * time.Minute
+)
+
+// checkPending requeues snapshots which are not ready yet with a backoff growing with
+// their age. Once a snapshot is pending for longer than the timeout, the cause is
+// derived from the host objects and set as error condition on the virtual snapshot.
+// Errors of the host snapshot are escalated right away.
+func (s *snapshotSyncer) checkPending(
+	ctx *synccontext.SyncContext,
+	pSnapshot *snapshotv1.VolumeSnapshot,
+	vSnapshot *snapshotv1.VolumeSnapshot,
+) (ctrl.Result, error) {
+	if hasSnapshotCondition(pSnapshot, snapshotv1.VolumeSnapshotConditionReady) {
+		return ctrl.Result{}, s.clearEscalation(ctx, vSnapshot)
+	}
+	if condition := snapshotCondition(pSnapshot, snapshotv1.VolumeSnapshotConditionError); condition != nil {
+		return ctrl.Result{}, s.escalate(ctx, vSnapshot, reasonHostSnapshotError, condition.Message)
+	}
+	if s.pendingTimeout <= 0 {
+		return ctrl.Result{}, nil
+	}
+
+	age := time.Since(vSnapshot.CreationTimestamp.Time)
+	if age < s.pendingTimeout {
+		return ctrl.Result{RequeueAfter: pendingRequeueAfter(age, s.pendingTimeout)}, nil
+	}
+
+	reason, message, err := s.diagnosePending(ctx, pSnapshot, vSnapshot)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// keep checking, the snapshot may still become ready
+	return ctrl.Result{RequeueAfter: maxPendingRequeue}, s.escalate(ctx, vSnapshot, reason, message)
+}
+
+// checkNotSynced escalates errors creating the host snapshot once the virtual snapshot
+// is pending for longer than the timeout.
+func (s *snapshotSyncer) checkNotSynced(ctx *synccontext.SyncContext, vSnapshot *snapshotv1.VolumeSnapshot, syncErr error) error {
+	if s.pendingTimeout <= 0 || time.Since(vSnapshot.CreationTimestamp.Time) < s.pendingTimeout {
+		return nil
+	}
+
+	return s.escalate(ctx, vSnapshot, reasonHostSnapshotNotSynced, syncErr.Error())
+}
+
+// diagnosePending inspects the host objects of a pending snapshot for the reason it is
+// not ready.
+func (s *snapshotSyncer) diagnosePending(
+	ctx *synccontext.SyncContext,
+	pSnapshot *snapshotv1.VolumeSnapshot,
+	vSnapshot *snapshotv1.VolumeSnapshot,
+) (string, string, error) {
+	pvcName := vSnapshot.Spec.PersistentVolumeClaimName
+	pPVC := &corev1.PersistentVolumeClaim{}
+	err := ctx.PhysicalClient.Get(ctx.Context, client.ObjectKey{
+		Namespace: ctx.TargetNamespace,
+		Name:      translate.PhysicalName(pvcName, vSnapshot.Namespace),
+	}, pPVC)
+	if kerrors.IsNotFound(err) {
+		return reasonHostPVCNotFound, fmt.Sprintf("persistentvolumeclaim %s is not synced to the host cluster", pvcName), nil
+	} else if err != nil {
+		return "", "", errors.Wrap(err, "get physical persistent volume claim")
+	}
+	if pPVC.Status.Phase != corev1.ClaimBound {
+		return reasonHostPVCNotBound, fmt.Sprintf("persistentvolumeclaim %s is %s in the host cluster", pvcName, pPVC.Status.Phase), nil
+	}
+
+	if len(pSnapshot.Status.Conditions) == 0 && pSnapshot.Spec.SnapshotDataName == "" {
+		return reasonControllerNotRunning, fmt.Sprintf(
+			"the snapshot was not picked up by a snapshot controller of the host cluster within %s, make sure Stork is running",
+			s.pendingTimeout,
+		), nil
+	}
+
+	message := fmt.Sprintf("the snapshot is not ready after %s", s.pendingTimeout)
+	if condition := snapshotCondition(pSnapshot, snapshotv1.VolumeSnapshotConditionPending); condition != nil && condition.Message != "" {
+		message += ": " + condition.Message
+	}
+	return reasonPendingTimeout, message, nil
+}
+
+// escalate sets the error condition on the virtual snapshot and records an event, unless
+// the condition is already set with the same reason and message.
+func (s *snapshotSyncer) escalate(ctx *synccontext.SyncContext, vSnapshot *snapshotv1.VolumeSnapshot, reason, message string) error {
+	if condition := snapshotCondition(vSnapshot, snapshotv1.VolumeSnapshotConditionError); condition != nil &&
+		condition.Status == corev1.ConditionTrue && condition.Reason == reason && condition.Message == message {
+		return nil
+	}
+
+	updated := vSnapshot.DeepCopy()
+	conditions := withoutSnapshotCondition(updated.Status.Conditions, snapshotv1.VolumeSnapshotConditionError)
+	updated.Status.Conditions = append(conditions, snapshotv1.VolumeSnapshotCondition{
+		Type:               snapshotv1.VolumeSnapshotConditionError,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+
+	ctx.Log.Infof("set error condition on virtual volume snapshot %s/%s: %s", vSnapshot.Namespace, vSnapshot.Name, reason)
+	if err := ctx.VirtualClient.Update(ctx.Context, updated); err != nil {
+		return errors.Wrap(err, "update virtual volume snapshot status")
+	}
+	s.EventRecorder().Eventf(vSnapshot, corev1.EventTypeWarning, reason, "Snapshot failed: %s", message)
+	return nil
+}
+
+// clearEscalation removes the error condition from a virtual snapshot which became ready
+// after all.
+func (s *snapshotSyncer) clearEscalation(ctx *synccontext.SyncContext, vSnapshot *snapshotv1.VolumeSnapshot) error {
+	if snapshotCondition(vSnapshot, snapshotv1.VolumeSnapshotConditionError) == nil {
+		return nil
+	}
+
+	updated := vSnapshot.DeepCopy()
+	updated.Status.Conditions = withoutSnapshotCondition(updated.Status.Conditions, snapshotv1.VolumeSnapshotConditionError)
+	ctx.Log.Infof("remove error condition from virtual volume snapshot %s/%s", vSnapshot.Namespace, vSnapshot.Name)
+	return errors.Wrap(ctx.VirtualClient.Update(ctx.Context, updated), "update virtual volume snapshot status")
+}
+
+// pendingRequeueAfter returns the delay until a pending snapshot is checked again, which
+// grows with the age of the snapshot, but never passes the timeout.
+func pendingRequeueAfter(age, timeout time.Duration) time.Duration {
+	after := age
+	if after < minPendingRequeue {
+		after = minPendingRequeue
+	} else if after > maxPendingRequeue {
+		after = maxPendingRequeue
+	}
+	if remaining := timeout - age; remaining > 0 && remaining < after {
+		after = remaining
+	}
+	return after
+}
+
+func hasSnapshotCondition(snapshot *snapshotv1.VolumeSnapshot, conditionType snapshotv1.VolumeSnapshotConditionType) bool {
+	condition := snapshotCondition(snapshot, conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
+
+func snapshotCondition(
+	snapshot *snapshotv1.VolumeSnapshot,
+	conditionType snapshotv1.VolumeSnapshotConditionType,
+) *snapshotv1.VolumeSnapshotCondition {
+	for i := range snapshot.Status.Conditions {
+		if snapshot.Status.Conditions[i].Type == conditionType {
+			return &snapshot.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func withoutSnapshotCondition(
+	conditions []snapshotv1.VolumeSnapshotCondition,
+	conditionType snapshotv1.VolumeSnapshotConditionType,
+) []snapshotv1.VolumeSnapshotCondition {
+	filtered := []snapshotv1.VolumeSnapshotCondition{}
+	for _, condition := range conditions {
+		if condition.Type != conditionType {
+			filtered = append(filtered, condition)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,89 @@
+package syncers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/webhook"
+)
+
+// NewSnapshotValidator returns a webhook rejecting virtual VolumeSnapshots which would
+// otherwise only fail once they are synced to the host cluster. Only snapshots imported
+// by the plugin itself skip the validation.
+func NewSnapshotValidator(ctx *synccontext.RegisterContext) webhook.Hook {
+	pluginUsername, err := webhook.Username(ctx.VirtualManager.GetConfig())
+	if err != nil {
+		log.New(webhook.ServerName).Errorf("error getting the user of the plugin, imported snapshots are validated: %v", err)
+	}
+
+	return webhook.Hook{
+		Name: "volumesnapshots.pxe.portworx.io",
+		Rules: []admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{snapshotv1.GroupName},
+				APIVersions: []string{snapshotv1.SchemeGroupVersion.Version},
+				Resources:   []string{snapshotv1.VolumeSnapshotResourcePlural},
+			},
+		}},
+		Handler: newSnapshotValidator(ctx.VirtualManager.GetAPIReader(), pluginUsername),
+	}
+}
+
+func newSnapshotValidator(virtualReader client.Reader, pluginUsername string) *snapshotValidator {
+	// the scheme is static, so creating the decoder can't fail
+	decoder, _ := admission.NewDecoder(plugin.Scheme)
+	return &snapshotValidator{
+		virtualReader:  virtualReader,
+		pluginUsername: pluginUsername,
+		decoder:        decoder,
+	}
+}
+
+type snapshotValidator struct {
+	virtualReader  client.Reader
+	pluginUsername string
+	decoder        *admission.Decoder
+}
+
+var _ admission.Handler = &snapshotValidator{}
+
+func (v *snapshotValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	vSnapshot := &snapshotv1.VolumeSnapshot{}
+	if err := v.decoder.Decode(req, vSnapshot); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// imported snapshots are created by the plugin for existing host snapshots, the
+	// annotation alone can be set by anyone
+	if isImported(vSnapshot) && v.pluginUsername != "" && req.UserInfo.Username == v.pluginUsername {
+		return admission.Allowed("")
+	}
+
+	pvcName := vSnapshot.Spec.PersistentVolumeClaimName
+	if pvcName == "" {
+		return admission.Denied("spec.persistentVolumeClaimName is required")
+	}
+	err := v.virtualReader.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: pvcName}, &corev1.PersistentVolumeClaim{})
+	if kerrors.IsNotFound(err) {
+		return admission.Denied(fmt.Sprintf("persistentvolumeclaim %s does not exist", pvcName))
+	} else if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if _, err := validateSecretAnnotations(ctx, v.virtualReader, req.Namespace, vSnapshot.Annotations); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
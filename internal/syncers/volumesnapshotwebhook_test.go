@@ -0,0 +1,152 @@
+package syncers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+)
+
+const testPluginUsername = "system:serviceaccount:vcluster:pxe-vcluster"
+
+func newSnapshotRequest(t *testing.T, username string, vSnapshot *snapshotv1.VolumeSnapshot) admission.Request {
+	t.Helper()
+
+	raw, err := json.Marshal(vSnapshot)
+	if err != nil {
+		t.Fatalf("marshal snapshot: %v", err)
+	}
+	return snapshotRequest(username, raw)
+}
+
+func snapshotRequest(username string, raw []byte) admission.Request {
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		UID:       "1234",
+		Kind:      metav1.GroupVersionKind{Group: snapshotv1.GroupName, Version: "v1", Kind: "VolumeSnapshot"},
+		Namespace: "ns",
+		Name:      "snap",
+		Operation: admissionv1.Create,
+		UserInfo:  authenticationv1.UserInfo{Username: username},
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func testSnapshot(pvcName string, annotations map[string]string) *snapshotv1.VolumeSnapshot {
+	return &snapshotv1.VolumeSnapshot{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: snapshotv1.SchemeGroupVersion.String(),
+			Kind:       "VolumeSnapshot",
+		},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "snap", Annotations: annotations},
+		Spec:       snapshotv1.VolumeSnapshotSpec{PersistentVolumeClaimName: pvcName},
+	}
+}
+
+func TestSnapshotValidatorHandle(t *testing.T) {
+	virtualReader := fake.NewClientBuilder().WithObjects(
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "data"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "key"},
+			Data:       map[string][]byte{"passphrase": []byte("secret")},
+		},
+	).Build()
+	imported := map[string]string{ImportedFromAnnotation: "host/snap"}
+
+	tests := []struct {
+		name    string
+		request admission.Request
+		allowed bool
+		code    int32
+	}{
+		{
+			name:    "valid snapshot",
+			request: newSnapshotRequest(t, "tenant", testSnapshot("data", nil)),
+			allowed: true,
+			code:    http.StatusOK,
+		},
+		{
+			name:    "missing persistent volume claim name",
+			request: newSnapshotRequest(t, "tenant", testSnapshot("", nil)),
+			code:    http.StatusForbidden,
+		},
+		{
+			name:    "missing persistent volume claim",
+			request: newSnapshotRequest(t, "tenant", testSnapshot("missing", nil)),
+			code:    http.StatusForbidden,
+		},
+		{
+			name: "valid encryption secret",
+			request: newSnapshotRequest(t, "tenant", testSnapshot("data", map[string]string{
+				pxSecretNameAnnotation: "key",
+				pxSecretKeyAnnotation:  "passphrase",
+			})),
+			allowed: true,
+			code:    http.StatusOK,
+		},
+		{
+			name: "missing encryption secret",
+			request: newSnapshotRequest(t, "tenant", testSnapshot("data", map[string]string{
+				pxSecretNameAnnotation: "missing",
+			})),
+			code: http.StatusForbidden,
+		},
+		{
+			name: "missing encryption secret key",
+			request: newSnapshotRequest(t, "tenant", testSnapshot("data", map[string]string{
+				pxSecretNameAnnotation: "key",
+				pxSecretKeyAnnotation:  "missing",
+			})),
+			code: http.StatusForbidden,
+		},
+		{
+			name:    "imported by the plugin",
+			request: newSnapshotRequest(t, testPluginUsername, testSnapshot("missing", imported)),
+			allowed: true,
+			code:    http.StatusOK,
+		},
+		{
+			name:    "imported annotation set by a tenant",
+			request: newSnapshotRequest(t, "tenant", testSnapshot("missing", imported)),
+			code:    http.StatusForbidden,
+		},
+		{
+			name:    "invalid object",
+			request: snapshotRequest("tenant", []byte("{")),
+			code:    http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newSnapshotValidator(virtualReader, testPluginUsername)
+
+			response := v.Handle(context.Background(), tt.request)
+			if response.Allowed != tt.allowed {
+				t.Errorf("expected allowed %v, got %v (%v)", tt.allowed, response.Allowed, response.Result)
+			}
+			if response.Result == nil || response.Result.Code != tt.code {
+				t.Errorf("expected code %d, got %v", tt.code, response.Result)
+			}
+		})
+	}
+}
+
+func TestSnapshotValidatorWithoutPluginUsername(t *testing.T) {
+	v := newSnapshotValidator(fake.NewClientBuilder().Build(), "")
+
+	imported := testSnapshot("missing", map[string]string{ImportedFromAnnotation: "host/snap"})
+	response := v.Handle(context.Background(), newSnapshotRequest(t, "", imported))
+	if response.Allowed {
+		t.Errorf("expected imported snapshot of an unknown user to be validated")
+	}
+}
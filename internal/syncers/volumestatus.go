@@ -0,0 +1,280 @@
+package syncers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/loft-sh/vcluster-sdk/syncer/translator"
+	"github.com/loft-sh/vcluster-sdk/translate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snapshotv1 "github.com/portworx/pxe-vcluster/apis/external-storage/snapshot/v1"
+	"github.com/portworx/pxe-vcluster/internal/metrics"
+	"github.com/portworx/pxe-vcluster/internal/openstorage"
+)
+
+// Annotations set on virtual PVCs with the status of their Portworx volume.
+const (
+	volumeStatusPrefix = "status.pxe.portworx.io/"
+
+	VolumeUsedBytesAnnotation = volumeStatusPrefix + "used-bytes"
+	VolumeReplicasAnnotation  = volumeStatusPrefix + "replicas"
+	VolumeHALevelAnnotation   = volumeStatusPrefix + "ha-level"
+	VolumeHealthAnnotation    = volumeStatusPrefix + "health"
+	VolumeIOProfileAnnotation = volumeStatusPrefix + "io-profile"
+)
+
+const (
+	volumeStatusReporterName = "px-volume-status"
+	// volumeHealthDegraded is reported for volumes which are up with missing replicas
+	volumeHealthDegraded = "degraded"
+)
+
+// NewVolumeStatusReporter returns a controller which periodically inspects the Portworx
+// volumes of virtual PVCs through the SDK and publishes their usage and health as
+// annotations on the virtual PVCs and as metrics. If tokenSecret is set, requests are
+// authenticated with the token of the vcluster.
+func NewVolumeStatusReporter(
+	ctx *synccontext.RegisterContext,
+	endpoint string,
+	tokenSecret string,
+	interval time.Duration,
+) syncer.Base {
+	return &volumeStatusReporter{
+		endpoint:    endpoint,
+		tokenSecret: tokenSecret,
+		interval:    interval,
+		log:         log.New(volumeStatusReporterName),
+		reported:    map[client.ObjectKey]bool{},
+	}
+}
+
+type volumeStatusReporter struct {
+	endpoint    string
+	tokenSecret string
+	interval    time.Duration
+	log         log.Logger
+
+	sdk            *openstorage.Client
+	virtualClient  client.Client
+	physicalClient client.Client
+	targetNs       string
+
+	// reported are the virtual PVCs with metrics
+	reported map[client.ObjectKey]bool
+}
+
+func (r *volumeStatusReporter) Name() string {
+	return volumeStatusReporterName
+}
+
+var _ syncer.ControllerStarter = &volumeStatusReporter{}
+
+func (r *volumeStatusReporter) Register(ctx *synccontext.RegisterContext) error {
+	r.virtualClient = ctx.VirtualManager.GetClient()
+	r.physicalClient = ctx.PhysicalManager.GetClient()
+	r.targetNs = ctx.TargetNamespace
+
+	sdk, err := newSDKClient(ctx, r.endpoint, r.tokenSecret)
+	if err != nil {
+		return err
+	}
+	r.sdk = sdk
+
+	go func() {
+		wait.UntilWithContext(ctx.Context, r.report, r.interval)
+		_ = r.sdk.Close()
+	}()
+
+	return nil
+}
+
+func (r *volumeStatusReporter) report(ctx context.Context) {
+	vPVCs := &corev1.PersistentVolumeClaimList{}
+	if err := r.virtualClient.List(ctx, vPVCs); err != nil {
+		r.log.Errorf("error listing virtual persistent volume claims: %v", err)
+		return
+	}
+
+	seen := map[client.ObjectKey]bool{}
+	for i := range vPVCs.Items {
+		vPVC := &vPVCs.Items[i]
+		if vPVC.Status.Phase != corev1.ClaimBound {
+			continue
+		}
+
+		volume, err := r.inspectVolume(ctx, vPVC)
+		if err != nil {
+			r.log.Infof("error inspecting portworx volume of %s/%s: %v", vPVC.Namespace, vPVC.Name, err)
+			continue
+		} else if volume == nil {
+			continue
+		}
+
+		seen[client.ObjectKeyFromObject(vPVC)] = true
+		r.setMetrics(vPVC, volume)
+		if err := r.annotate(ctx, vPVC, volume); err != nil {
+			r.log.Infof("error annotating %s/%s: %v", vPVC.Namespace, vPVC.Name, err)
+		}
+	}
+
+	// drop metrics of PVCs which are gone or no longer backed by portworx
+	for key := range r.reported {
+		if !seen[key] {
+			for _, c := range metrics.VolumeCollectors {
+				c.DeleteLabelValues(translate.Suffix, key.Namespace, key.Name)
+			}
+		}
+	}
+	r.reported = seen
+}
+
+// inspectVolume returns the Portworx volume of the virtual PVC or nil if the PVC isn't
+// backed by Portworx.
+func (r *volumeStatusReporter) inspectVolume(ctx context.Context, vPVC *corev1.PersistentVolumeClaim) (*openstorage.Volume, error) {
+	pPVC := &corev1.PersistentVolumeClaim{}
+	if err := r.physicalClient.Get(ctx, client.ObjectKey{
+		Namespace: r.targetNs,
+		Name:      translate.PhysicalName(vPVC.Name, vPVC.Namespace),
+	}, pPVC); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get physical persistent volume claim")
+	}
+	if pPVC.Spec.VolumeName == "" {
+		return nil, nil
+	}
+
+	pv := &corev1.PersistentVolume{}
+	if err := r.physicalClient.Get(ctx, client.ObjectKey{Name: pPVC.Spec.VolumeName}, pv); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get persistent volume")
+	}
+
+	volumeID := portworxVolumeID(pv)
+	if volumeID == "" {
+		return nil, nil
+	}
+
+	return r.sdk.InspectVolume(ctx, volumeID)
+}
+
+func (r *volumeStatusReporter) setMetrics(vPVC *corev1.PersistentVolumeClaim, volume *openstorage.Volume) {
+	labels := []string{translate.Suffix, vPVC.Namespace, vPVC.Name}
+	metrics.VolumeUsedBytes.WithLabelValues(labels...).Set(float64(volume.Usage))
+	metrics.VolumeCapacityBytes.WithLabelValues(labels...).Set(float64(volume.Spec.Size))
+	metrics.VolumeReplicas.WithLabelValues(labels...).Set(float64(replicaCount(volume)))
+	metrics.VolumeHALevel.WithLabelValues(labels...).Set(float64(volume.Spec.HALevel))
+
+	healthy := 0.0
+	if volumeHealth(volume) == openstorage.VolumeStatusUp.String() {
+		healthy = 1
+	}
+	metrics.VolumeHealthy.WithLabelValues(labels...).Set(healthy)
+}
+
+func (r *volumeStatusReporter) annotate(ctx context.Context, vPVC *corev1.PersistentVolumeClaim, volume *openstorage.Volume) error {
+	updated := vPVC.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[VolumeUsedBytesAnnotation] = strconv.FormatUint(volume.Usage, 10)
+	updated.Annotations[VolumeReplicasAnnotation] = strconv.Itoa(replicaCount(volume))
+	updated.Annotations[VolumeHALevelAnnotation] = strconv.FormatInt(volume.Spec.HALevel, 10)
+	updated.Annotations[VolumeHealthAnnotation] = volumeHealth(volume)
+	updated.Annotations[VolumeIOProfileAnnotation] = volume.Spec.IoProfile.String()
+	if equality.Semantic.DeepEqual(updated.Annotations, vPVC.Annotations) {
+		return nil
+	}
+
+	return r.virtualClient.Patch(ctx, updated, client.MergeFrom(vPVC))
+}
+
+// newSDKClient returns a client for the OpenStorage SDK, which authenticates with the
+// token in the host Secret tokenSecret if set.
+func newSDKClient(ctx *synccontext.RegisterContext, endpoint, tokenSecret string) (*openstorage.Client, error) {
+	opts := openstorage.Options{Endpoint: endpoint}
+	if tokenSecret != "" {
+		opts.Token = openstorage.SecretTokenSource(
+			ctx.PhysicalManager.GetAPIReader(),
+			client.ObjectKey{Namespace: ctx.TargetNamespace, Name: tokenSecret},
+			tokenSecretKey,
+		)
+	}
+
+	sdk, err := openstorage.NewClient(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "create openstorage client")
+	}
+
+	return sdk, nil
+}
+
+// replicaCount returns the number of nodes holding a replica of the volume.
+func replicaCount(volume *openstorage.Volume) int {
+	if len(volume.ReplicaSets) == 0 {
+		return 0
+	}
+	return len(volume.ReplicaSets[0].Nodes)
+}
+
+// volumeHealth returns the status of the volume, or "degraded" if the volume is up but
+// has fewer replicas than requested.
+func volumeHealth(volume *openstorage.Volume) string {
+	if volume.Status == openstorage.VolumeStatusUp && int64(replicaCount(volume)) < volume.Spec.HALevel {
+		return volumeHealthDegraded
+	}
+	return volume.Status.String()
+}
+
+// portworxVolumeID returns the id of the Portworx volume backing the persistent volume
+// or an empty string for volumes of other drivers.
+func portworxVolumeID(pv *corev1.PersistentVolume) string {
+	if pv.Spec.CSI != nil && snapshotv1.GetSupportedVolumeFromPVSpec(&pv.Spec) == "pxd" {
+		return pv.Spec.CSI.VolumeHandle
+	}
+	if pv.Spec.PortworxVolume != nil {
+		return pv.Spec.PortworxVolume.VolumeID
+	}
+	return ""
+}
+
+// stripVolumeStatus removes the status annotations of virtual PVCs, which vcluster would
+// otherwise copy to the host PVC. They are removed from the annotations vcluster manages
+// as well, so changing status annotations don't cause updates of the host PVC.
+func stripVolumeStatus(annotations map[string]string) {
+	for k := range annotations {
+		if strings.HasPrefix(k, volumeStatusPrefix) {
+			delete(annotations, k)
+		}
+	}
+
+	managed, ok := annotations[translator.ManagedAnnotationsAnnotation]
+	if !ok {
+		return
+	}
+	keys := []string{}
+	for _, k := range strings.Split(managed, "\n") {
+		if !strings.HasPrefix(k, volumeStatusPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		delete(annotations, translator.ManagedAnnotationsAnnotation)
+		return
+	}
+	annotations[translator.ManagedAnnotationsAnnotation] = strings.Join(keys, "\n")
+}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+)
+
+// Username returns the name of the user the given config authenticates as, which is the
+// user in the admission requests of the plugin. It supports impersonation, client
+// certificates, basic auth and service account tokens.
+func Username(config *rest.Config) (string, error) {
+	switch {
+	case config.Impersonate.UserName != "":
+		return config.Impersonate.UserName, nil
+	case len(config.CertData) > 0 || config.CertFile != "":
+		return certificateUsername(config)
+	case config.Username != "":
+		return config.Username, nil
+	case config.BearerToken != "" || config.BearerTokenFile != "":
+		return tokenUsername(config)
+	}
+
+	return "", errors.New("config has no credentials")
+}
+
+func certificateUsername(config *rest.Config) (string, error) {
+	data := config.CertData
+	if len(data) == 0 {
+		var err error
+		if data, err = os.ReadFile(config.CertFile); err != nil {
+			return "", errors.Wrap(err, "read client certificate")
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", errors.New("client certificate is not PEM encoded")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", errors.Wrap(err, "parse client certificate")
+	}
+
+	return certificate.Subject.CommonName, nil
+}
+
+// tokenUsername returns the subject of a JWT, e.g. the service account token of the
+// plugin. The token is not verified, it's the token the plugin authenticates with.
+func tokenUsername(config *rest.Config) (string, error) {
+	token := config.BearerToken
+	if token == "" {
+		data, err := os.ReadFile(config.BearerTokenFile)
+		if err != nil {
+			return "", errors.Wrap(err, "read token")
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.Wrap(err, "decode token claims")
+	}
+	claims := struct {
+		Subject string `json:"sub"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", errors.Wrap(err, "parse token claims")
+	}
+	if claims.Subject == "" {
+		return "", errors.New("token has no subject")
+	}
+
+	return claims.Subject, nil
+}
@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func testToken(claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"RS256"}`)) + "." + encode([]byte(claims)) + ".signature"
+}
+
+func TestUsername(t *testing.T) {
+	caBundle, _, err := newCertificates([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("generate certificates: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   *rest.Config
+		expected string
+		err      bool
+	}{
+		{
+			name:     "client certificate",
+			config:   &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: caBundle}},
+			expected: "pxe-vcluster-webhook-ca",
+		},
+		{
+			name:   "invalid client certificate",
+			config: &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: []byte("invalid")}},
+			err:    true,
+		},
+		{
+			name:     "service account token",
+			config:   &rest.Config{BearerToken: testToken(`{"sub":"system:serviceaccount:vcluster:pxe"}`)},
+			expected: "system:serviceaccount:vcluster:pxe",
+		},
+		{
+			name:   "token without subject",
+			config: &rest.Config{BearerToken: testToken(`{"iss":"kubernetes"}`)},
+			err:    true,
+		},
+		{
+			name:   "opaque token",
+			config: &rest.Config{BearerToken: "opaque"},
+			err:    true,
+		},
+		{
+			name:     "basic auth",
+			config:   &rest.Config{Username: "admin"},
+			expected: "admin",
+		},
+		{
+			name: "impersonation",
+			config: &rest.Config{
+				Username:    "admin",
+				Impersonate: rest.ImpersonationConfig{UserName: "plugin"},
+			},
+			expected: "plugin",
+		},
+		{
+			name:   "no credentials",
+			config: &rest.Config{},
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, err := Username(tt.config)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if username != tt.expected {
+				t.Errorf("expected username %q, got %q", tt.expected, username)
+			}
+		})
+	}
+}
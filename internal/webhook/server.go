@@ -0,0 +1,235 @@
+// Package webhook serves the admission webhooks of the plugin to the virtual cluster.
+//
+// The webhooks are served with a certificate generated on start and registered through
+// a ValidatingWebhookConfiguration and a MutatingWebhookConfiguration in the virtual
+// cluster. By default they are served on the loopback interface, which only works for
+// distros running the virtual API server in the pod of the plugin, like k3s and k0s.
+// With the API server in its own pod, like with the k8s and eks distros, the webhooks
+// must be served on an address the API server reaches and the host the API server
+// connects to must be set, e.g. the pod IP. The configurations are deleted by
+// Unregister when the server is disabled.
+//
+// Kinds plug in by providing a Hook, whose handler is a plain admission.Handler and can
+// be tested without the server.
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/loft-sh/vcluster-sdk/log"
+	"github.com/loft-sh/vcluster-sdk/syncer"
+	synccontext "github.com/loft-sh/vcluster-sdk/syncer/context"
+	"github.com/pkg/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// ServerName is the name of the webhook server, which enables it through PXE_SYNC.
+	ServerName = "webhooks"
+	// ConfigurationName is the name of the webhook configurations in the virtual cluster.
+	ConfigurationName = "pxe-vcluster"
+
+	webhookTimeoutSeconds = int32(10)
+)
+
+// Hook is an admission webhook for one or more kinds of the virtual cluster.
+type Hook struct {
+	// Name is the fully qualified name of the webhook, e.g. volumesnapshots.pxe.portworx.io
+	Name string
+	// Mutating registers the hook as mutating instead of validating webhook
+	Mutating bool
+	// Rules select the requests sent to the hook
+	Rules []admissionregistrationv1.RuleWithOperations
+	// Handler handles the admission requests
+	Handler admission.Handler
+}
+
+func (h Hook) path() string {
+	if h.Mutating {
+		return "/mutate/" + h.Name
+	}
+	return "/validate/" + h.Name
+}
+
+// NewServer returns the webhook server for the given hooks, listening on address. The
+// virtual API server calls the hooks at host, which defaults to the host of address or
+// the loopback interface.
+func NewServer(
+	address string,
+	host string,
+	failurePolicy admissionregistrationv1.FailurePolicyType,
+	hooks ...Hook,
+) *Server {
+	return &Server{
+		address:       address,
+		host:          host,
+		failurePolicy: failurePolicy,
+		hooks:         hooks,
+		log:           log.New(ServerName),
+	}
+}
+
+// Server serves the hooks and registers them in the virtual cluster.
+type Server struct {
+	address       string
+	host          string
+	failurePolicy admissionregistrationv1.FailurePolicyType
+	hooks         []Hook
+	log           log.Logger
+}
+
+func (s *Server) Name() string {
+	return ServerName
+}
+
+var _ syncer.ControllerStarter = &Server{}
+
+// Register starts serving the hooks and registers them afterwards, so the virtual API
+// server never calls a webhook which is not served yet.
+func (s *Server) Register(ctx *synccontext.RegisterContext) error {
+	host, port, err := net.SplitHostPort(s.address)
+	if err != nil {
+		return errors.Wrapf(err, "invalid webhook address %s", s.address)
+	}
+	if s.host != "" {
+		host = s.host
+	} else if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	caBundle, certificate, err := newCertificates([]string{host, "localhost"})
+	if err != nil {
+		return errors.Wrap(err, "generate webhook certificates")
+	}
+
+	listener, err := tls.Listen("tcp", s.address, &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return errors.Wrap(err, "listen")
+	}
+
+	mux := http.NewServeMux()
+	for _, hook := range s.hooks {
+		mux.Handle(hook.path(), &admission.Webhook{Handler: hook.Handler})
+	}
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Context.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		s.log.Infof("Serving %d webhooks on %s", len(s.hooks), s.address)
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.log.Errorf("error serving webhooks: %v", err)
+		}
+	}()
+
+	baseURL := "https://" + net.JoinHostPort(host, port)
+	return s.register(ctx.Context, ctx.VirtualManager.GetClient(), ctx.VirtualManager.GetAPIReader(), baseURL, caBundle)
+}
+
+// register replaces the webhooks of the configurations in the virtual cluster with the
+// hooks of the server. Configurations without hooks are deleted.
+func (s *Server) register(ctx context.Context, c client.Client, reader client.Reader, baseURL string, caBundle []byte) error {
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	timeoutSeconds := webhookTimeoutSeconds
+	failurePolicy := s.failurePolicy
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigurationName},
+	}
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigurationName},
+	}
+	for _, hook := range s.hooks {
+		url := baseURL + hook.path()
+		clientConfig := admissionregistrationv1.WebhookClientConfig{URL: &url, CABundle: caBundle}
+		if hook.Mutating {
+			mutating.Webhooks = append(mutating.Webhooks, admissionregistrationv1.MutatingWebhook{
+				Name:                    hook.Name,
+				ClientConfig:            clientConfig,
+				Rules:                   hook.Rules,
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				TimeoutSeconds:          &timeoutSeconds,
+				AdmissionReviewVersions: []string{"v1"},
+			})
+		} else {
+			validating.Webhooks = append(validating.Webhooks, admissionregistrationv1.ValidatingWebhook{
+				Name:                    hook.Name,
+				ClientConfig:            clientConfig,
+				Rules:                   hook.Rules,
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				TimeoutSeconds:          &timeoutSeconds,
+				AdmissionReviewVersions: []string{"v1"},
+			})
+		}
+	}
+
+	err := applyConfiguration(ctx, c, reader, &admissionregistrationv1.ValidatingWebhookConfiguration{}, validating, len(validating.Webhooks) == 0)
+	if err != nil {
+		return errors.Wrap(err, "register validating webhooks")
+	}
+	err = applyConfiguration(ctx, c, reader, &admissionregistrationv1.MutatingWebhookConfiguration{}, mutating, len(mutating.Webhooks) == 0)
+	if err != nil {
+		return errors.Wrap(err, "register mutating webhooks")
+	}
+
+	return nil
+}
+
+// Unregister deletes the webhook configurations of the server from the virtual cluster,
+// so a disabled server doesn't leave webhooks behind which can't be called anymore.
+func Unregister(ctx context.Context, config *rest.Config) error {
+	c, err := client.New(config, client.Options{})
+	if err != nil {
+		return errors.Wrap(err, "create virtual client")
+	}
+
+	for _, obj := range []client.Object{
+		&admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: ConfigurationName}},
+		&admissionregistrationv1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: ConfigurationName}},
+	} {
+		if err := c.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return errors.Wrapf(err, "delete webhook configuration %s", ConfigurationName)
+		}
+	}
+
+	return nil
+}
+
+// applyConfiguration creates or replaces the desired configuration, or deletes it if it
+// is empty. The configuration is read once through the API reader, so the cache of the
+// manager doesn't start an informer for every webhook configuration of the cluster.
+func applyConfiguration(ctx context.Context, c client.Client, reader client.Reader, existing, desired client.Object, empty bool) error {
+	err := reader.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	if kerrors.IsNotFound(err) {
+		if empty {
+			return nil
+		}
+		return c.Create(ctx, desired)
+	} else if err != nil {
+		return err
+	}
+
+	if empty {
+		return client.IgnoreNotFound(c.Delete(ctx, existing))
+	}
+	desired.SetResourceVersion(existing.GetResourceVersion())
+	return c.Update(ctx, desired)
+}